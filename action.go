@@ -0,0 +1,128 @@
+package updatecheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// ActionType is what happens when the user chooses an Action.
+type ActionType string
+
+const (
+	// ActionOpenURL opens the action's URL in the user's browser.
+	ActionOpenURL ActionType = "open-url"
+	// ActionRunUpgrade runs the upgrade function provided by the host application with WithUpgradeFunc().
+	ActionRunUpgrade ActionType = "run-upgrade"
+	// ActionSnooze hides update messages for the action's duration, or a day if it isn't set.
+	ActionSnooze ActionType = "snooze"
+)
+
+// defaultSnooze is how long ActionSnooze hides messages for if the server doesn't set a duration.
+const defaultSnooze = 24 * time.Hour
+
+// Action is an optional action attached to a message, which hosts such as
+// TUI applications can render as a button or keybinding and run with RunAction.
+type Action struct {
+	// Label is the text to display, such as "Release notes".
+	Label string `json:"label"`
+	// Type is what happens when the action is chosen.
+	Type ActionType `json:"type"`
+	// URL is the link opened by ActionOpenURL.
+	URL string `json:"url,omitempty"`
+	// Duration is how long ActionSnooze hides messages for, such as "72h".
+	Duration string `json:"duration,omitempty"`
+}
+
+// validAction sanitizes an action from the server, returning false if it should be dropped:
+// unknown action types, links which aren't allowed and invalid snooze durations.
+func validAction(a *Action, o Options) bool {
+	a.Label = toSingleLine(sanitizeMessage(a.Label))
+	a.URL = sanitizeMessage(a.URL)
+	if a.Label == "" {
+		return false
+	}
+	switch a.Type {
+	case ActionOpenURL:
+		return o.linkAllowed(a.URL)
+	case ActionRunUpgrade:
+		return true
+	case ActionSnooze:
+		if a.Duration == "" {
+			return true
+		}
+		d, err := time.ParseDuration(a.Duration)
+		return err == nil && d > 0
+	}
+	return false
+}
+
+// actionsFrom returns the valid actions from a response.
+func actionsFrom(actions []Action, o Options) []Action {
+	var valid []Action
+	for _, a := range actions {
+		if validAction(&a, o) {
+			valid = append(valid, a)
+		}
+	}
+	return valid
+}
+
+// RunAction runs an action from a message, such as one returned in Result.Actions by CheckNow.
+// The options should be the same as those used for the check.
+func RunAction(ctx context.Context, app App, a Action, opts ...func(*Options)) error {
+	o, err := NewOptions(true, opts...)
+	if err != nil {
+		return err
+	}
+	if !validAction(&a, o) {
+		return fmt.Errorf("invalid %q action %q", a.Type, a.Label)
+	}
+
+	switch a.Type {
+	case ActionOpenURL:
+		return openURL(a.URL)
+	case ActionRunUpgrade:
+		if o.UpgradeFunc == nil {
+			return errors.New("no upgrade function is configured, use WithUpgradeFunc()")
+		}
+		return o.UpgradeFunc(ctx)
+	case ActionSnooze:
+		d := defaultSnooze
+		if a.Duration != "" {
+			d, _ = time.ParseDuration(a.Duration)
+		}
+		return Snooze(app, d, opts...)
+	}
+	return nil
+}
+
+// Snooze hides update messages for app, and skips checks, for d.
+// The options should be the same as those used for checks, so that a clock set
+// with WithClock is used to decide when the snooze ends.
+func Snooze(app App, d time.Duration, opts ...func(*Options)) error {
+	o, err := NewOptions(true, opts...)
+	if err != nil {
+		return err
+	}
+	vc, _ := loadVersionConfig(app, o.log())
+	vc.SnoozedUntil = o.now().Add(d)
+	return vc.Save()
+}
+
+// openURL opens u in the user's browser.
+func openURL(u string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		cmd = exec.Command("xdg-open", u)
+	}
+	return cmd.Start()
+}
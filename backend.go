@@ -0,0 +1,398 @@
+package updatecheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Backend is a source of update information.
+// The default backend calls the Common Fate update service over HTTP,
+// but consumers can implement their own, such as one which reads a release
+// manifest from an internal artifact server, while reusing the scheduling,
+// caching and printing in this package.
+type Backend interface {
+	// Check returns whether an update is available for the application in req.
+	Check(ctx context.Context, req Request) (Response, error)
+}
+
+// Request and Response define the wire format used by the update service.
+// New fields may be added over time, but existing JSON field names and types
+// must not change between library versions, as the server relies on them.
+// The golden files in testdata pin the request format, see golden_test.go.
+type Request struct {
+	// Application is the app we are checking for updates to.
+	Application App `json:"application"`
+	// Version is the current version.
+	Version string `json:"version"`
+	// Architecture is the operating system's architecture.
+	Architecture string `json:"arch,omitempty"`
+	// OS is the operating system.
+	OS string `json:"os,omitempty"`
+	// OSVersion is the operating system version or kernel release.
+	// It is only sent if enabled with WithOSVersion().
+	OSVersion string `json:"osVersion,omitempty"`
+	// UpdatePolicy restricts which releases the server should offer.
+	UpdatePolicy UpdatePolicy `json:"updatePolicy,omitempty"`
+	// Channel is the release channel the user receives updates from, such as "stable" or "nightly".
+	Channel Channel `json:"channel,omitempty"`
+	// InstallMethod is how the application was installed, such as "homebrew",
+	// so that the server can tailor its upgrade instructions.
+	InstallMethod InstallMethod `json:"installMethod,omitempty"`
+	// ClientID is a random anonymous identifier for this installation.
+	// It is only sent if enabled with WithAnonymousClientID().
+	ClientID string `json:"clientId,omitempty"`
+	// Cohort is a stable bucket between 0 and 99 derived from ClientID,
+	// which the server can use to experiment with message phrasing.
+	// It is only sent with ClientID.
+	Cohort *int `json:"cohort,omitempty"`
+}
+
+// Response is the result of an update check.
+type Response struct {
+	// UpdateRequired is true if there is a new version available
+	UpdateRequired bool `json:"updateRequired"`
+	// Message to display to the user. Can include security notifications.
+	Message string `json:"message"`
+	// LatestVersion is the latest available version, if the server provides it.
+	LatestVersion string `json:"latestVersion,omitempty"`
+	// MessageID identifies the message, so that it is only shown once
+	// when several endpoints return the same message.
+	MessageID string `json:"messageId,omitempty"`
+	// ReleaseDate is when the latest version was released, if the server provides it.
+	ReleaseDate time.Time `json:"releaseDate,omitempty"`
+	// Health is the server's advice about the stability of the latest version,
+	// such as ReleaseStabilizing for a release which is still being monitored after launch.
+	Health ReleaseHealth `json:"health,omitempty"`
+	// ExpiresAt is when the message stops being shown. Until then, the message is shown
+	// again when later checks are skipped by throttling. Defaults to a week after the check.
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
+	// Actions are optional actions for the message, such as opening the release notes.
+	Actions []Action `json:"actions,omitempty"`
+	// Critical is true for security advisories. If response signing is enabled,
+	// critical messages are only shown if the response is signed.
+	Critical bool `json:"critical,omitempty"`
+	// Required is true if the running version must be upgraded, such as a version with a known
+	// data-corrupting bug. The calling CLI can check for this with RequiredUpdate().
+	// If response signing is enabled, it is ignored unless the response is signed.
+	Required bool `json:"required,omitempty"`
+	// Changelog is the release notes for the versions since the running version,
+	// newest first. It is only shown by Print() if enabled with WithChangelog().
+	Changelog []Release `json:"changelog,omitempty"`
+	// NewEndpoint is the URL of the endpoint to use for future checks, which allows
+	// the update service to move without stranding old versions of the CLI.
+	// Permanent redirects (308) are handled in the same way.
+	NewEndpoint string `json:"newEndpoint,omitempty"`
+	// StopChecking tells the running version to stop checking for updates, such as for
+	// a product line which has reached end of life, so that retired versions don't keep
+	// calling the update service. It is remembered until the user changes version.
+	// It is ignored in responses from endpoints added with WithAdditionalEndpoint, and if
+	// response signing is enabled, it is ignored unless the response is signed.
+	StopChecking bool `json:"stopChecking,omitempty"`
+	// Messages are additional notices, such as deprecations and security advisories,
+	// which are displayed according to their severity.
+	Messages []Notice `json:"messages,omitempty"`
+
+	// serverTime is the time from the response's Date header, if present.
+	serverTime time.Time
+	// verified is true if the response was signed with the key configured by WithSignedResponses().
+	verified bool
+	// endpoint is the URL which was called, if the response came from an HTTP endpoint.
+	endpoint string
+	// redirectedTo is the URL which the endpoint permanently redirected to, if it did.
+	redirectedTo string
+	// conditional is stored so that the next check can make a conditional request.
+	conditional conditionalResponse
+}
+
+// Notice is an additional message in a Response.
+type Notice struct {
+	// ID identifies the notice, so that it is only shown once
+	// when several endpoints return the same notice.
+	ID string `json:"id,omitempty"`
+	// Severity is how important the notice is. Unknown severities are treated as SeverityInfo.
+	// If response signing is enabled, critical notices are only shown if the response is signed.
+	Severity Severity `json:"severity"`
+	// Message to display to the user.
+	Message string `json:"message"`
+}
+
+// severity returns the severity of the response's main message.
+func (r Response) severity() Severity {
+	if r.Critical {
+		return SeverityCritical
+	}
+	if r.UpdateRequired {
+		return SeverityUpdate
+	}
+	return SeverityInfo
+}
+
+// backends returns the backends to check, in order of priority.
+// A custom backend replaces the HTTP endpoints.
+func (o Options) backends(timings *Timings) []Backend {
+	if o.Backend != nil {
+		return []Backend{o.Backend}
+	}
+	// timings are only recorded for the primary endpoint.
+	backends := []Backend{httpBackend{url: o.URL, opts: o, timings: timings}}
+	for _, u := range o.AdditionalURLs {
+		backends = append(backends, httpBackend{url: u, opts: o})
+	}
+	return backends
+}
+
+// callEndpoints calls each backend concurrently.
+// The responses are returned in the order the backends were configured, skipping any which failed.
+// An error is only returned if every backend failed.
+func callEndpoints(ctx context.Context, app App, currentVersion string, o Options, timings *Timings) ([]Response, error) {
+	req := Request{
+		Application:   app,
+		Version:       currentVersion,
+		Architecture:  runtime.GOARCH,
+		OS:            runtime.GOOS,
+		UpdatePolicy:  o.UpdatePolicy,
+		Channel:       o.Channel,
+		InstallMethod: detectInstallMethod(),
+	}
+	if o.clientID != "" {
+		c := cohort(o.clientID)
+		req.ClientID = o.clientID
+		req.Cohort = &c
+	}
+	if o.IncludeOSVersion && o.telemetryAllowed() {
+		req.OSVersion = osVersion()
+	}
+	if o.MinimalTelemetry {
+		req = Request{Application: app, Version: currentVersion}
+	}
+
+	backends := o.backends(timings)
+	type result struct {
+		res Response
+		err error
+	}
+	results := make([]result, len(backends))
+	var wg sync.WaitGroup
+	for i, b := range backends {
+		wg.Add(1)
+		go func(i int, b Backend) {
+			defer wg.Done()
+			res, err := b.Check(ctx, req)
+			results[i] = result{res: res, err: err}
+		}(i, b)
+	}
+	wg.Wait()
+
+	var responses []Response
+	var err error
+	for i, r := range results {
+		if r.err != nil {
+			o.log().Debugf("error checking for updates using %s: %s", backendName(backends[i]), r.err.Error())
+			err = r.err
+			continue
+		}
+		responses = append(responses, r.res)
+	}
+	if len(responses) == 0 {
+		return nil, err
+	}
+	return responses, nil
+}
+
+// backendName describes a backend in debug logs.
+func backendName(b Backend) string {
+	if hb, ok := b.(httpBackend); ok {
+		return hb.url
+	}
+	return fmt.Sprintf("%T", b)
+}
+
+// httpBackend calls an update service endpoint over HTTP.
+type httpBackend struct {
+	url  string
+	opts Options
+	// timings is optional, and records how long each phase of the request took.
+	timings *Timings
+}
+
+func (h httpBackend) Check(ctx context.Context, cr Request) (Response, error) {
+	b := new(bytes.Buffer)
+	err := json.NewEncoder(b).Encode(cr)
+	if err != nil {
+		return Response{}, err
+	}
+	var ua string
+	if h.opts.MinimalTelemetry {
+		ua = minimalUserAgent()
+	} else {
+		ua = userAgent(h.opts)
+	}
+
+	if h.timings != nil {
+		pt := &phaseTimer{t: h.timings}
+		ctx = httptrace.WithClientTrace(ctx, pt.trace())
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := h.call(ctx, cr, b.Bytes(), ua)
+		if err == nil || attempt >= h.opts.Retries || !isTransient(err) || ctx.Err() != nil {
+			return resp, err
+		}
+		backoff := retryBackoff(h.opts.RetryBackoff, attempt)
+		h.opts.log().Debugf("retrying update check in %s after error: %s", backoff, err.Error())
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return Response{}, ctx.Err()
+		}
+	}
+}
+
+// statusError is returned when the update service responds with an unexpected status code.
+type statusError struct {
+	code int
+}
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("got invalid response from update checker API: %d", e.code)
+}
+
+// maxRetryBackoff is the longest time to wait between retries.
+const maxRetryBackoff = 10 * time.Second
+
+// retryBackoff returns how long to wait before retrying after the given attempt, doubling base
+// for each attempt up to maxRetryBackoff.
+func retryBackoff(base time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 0; i < attempt && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	if d > maxRetryBackoff || d <= 0 {
+		return maxRetryBackoff
+	}
+	return d
+}
+
+// isTransient returns true if a request which failed with err is worth retrying.
+// Server errors, rate limiting, timeouts and refused or reset connections are retried,
+// but not invalid responses, TLS or DNS failures, or an unreachable network.
+func isTransient(err error) bool {
+	var se statusError
+	if errors.As(err, &se) {
+		return se.code >= 500 || se.code == http.StatusTooManyRequests
+	}
+	if isNetworkUnreachable(err) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// call makes a single request to the update service, limited by the request timeout.
+func (h httpBackend) call(ctx context.Context, cr Request, reqBody []byte, ua string) (Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.opts.Timeout)
+	defer cancel()
+
+	smp := sample{Time: time.Now(), URL: h.url, Request: append(json.RawMessage(nil), reqBody...)}
+	defer func() { recordSample(smp) }()
+
+	req, _ := http.NewRequestWithContext(ctx, "POST", h.url, bytes.NewReader(reqBody))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("User-Agent", ua)
+	// a server-minted ETag can identify the client, so validators aren't sent with minimal telemetry.
+	cached, hasCached := h.opts.conditional[h.url]
+	if h.opts.MinimalTelemetry {
+		hasCached = false
+	}
+	if hasCached && cached.ETag != "" {
+		req.Header.Add(ifNoneMatchHeader, cached.ETag)
+	}
+	if hasCached && cached.LastModified != "" {
+		req.Header.Add(ifModifiedSinceHeader, cached.LastModified)
+	}
+	signing := len(h.opts.ResponsePublicKey) > 0
+	var nonce string
+	if signing {
+		var err error
+		nonce, err = newNonce()
+		if err != nil {
+			return Response{}, err
+		}
+		req.Header.Add(nonceHeader, nonce)
+	}
+
+	release, err := acquireRequestSlot(ctx)
+	if err != nil {
+		smp.Error = err.Error()
+		return Response{}, err
+	}
+	defer release()
+
+	res, err := h.opts.httpClient().Do(req)
+	if err != nil {
+		smp.Error = err.Error()
+		return Response{}, err
+	}
+	defer res.Body.Close()
+	smp.Status = res.StatusCode
+
+	var body []byte
+	var sig string
+	conditional := conditionalResponse{ETag: res.Header.Get("ETag"), LastModified: res.Header.Get("Last-Modified")}
+	switch {
+	case notModified(res.StatusCode) && hasCached:
+		h.opts.log().Debugf("update check response from %s has not changed", h.url)
+		body = []byte(cached.Body)
+		// the stored signature was for an earlier nonce, so the service signs the stored body again.
+		sig = res.Header.Get(signatureHeader)
+		if conditional.ETag == "" && conditional.LastModified == "" {
+			conditional.ETag, conditional.LastModified = cached.ETag, cached.LastModified
+		}
+	case res.StatusCode == http.StatusOK:
+		body, err = io.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+		if err != nil {
+			smp.Error = err.Error()
+			return Response{}, err
+		}
+		sig = res.Header.Get(signatureHeader)
+	default:
+		return Response{}, statusError{code: res.StatusCode}
+	}
+	smp.Response = string(body)
+
+	var resp Response
+	err = json.Unmarshal(body, &resp)
+	if err != nil {
+		smp.Error = err.Error()
+		return Response{}, err
+	}
+	if date, err := http.ParseTime(res.Header.Get("Date")); err == nil {
+		resp.serverTime = date
+	}
+	if signing {
+		resp.verified = verifyResponse(h.opts.log(), h.opts.ResponsePublicKey, signedPayload(cr.Application, cr.Version, nonce, body), sig)
+	}
+	resp.endpoint = h.url
+	conditional.Body = string(body)
+	resp.conditional = conditional
+	resp.redirectedTo = permanentlyRedirected(res)
+
+	return resp, nil
+}
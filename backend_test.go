@@ -0,0 +1,72 @@
+package updatecheck
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// timeoutError is a net.Error which timed out.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// urlError wraps err as http.Client.Do does.
+func urlError(err error) error {
+	return &url.Error{Op: "Post", URL: "https://update.example.com", Err: err}
+}
+
+func TestIsTransient(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "server error", err: statusError{code: 503}, want: true},
+		{name: "rate limited", err: statusError{code: 429}, want: true},
+		{name: "not found", err: statusError{code: 404}},
+		{name: "deadline", err: urlError(context.DeadlineExceeded), want: true},
+		{name: "timeout", err: urlError(timeoutError{}), want: true},
+		{name: "connection refused", err: urlError(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), want: true},
+		{name: "connection reset", err: urlError(&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), want: true},
+		{name: "network unreachable", err: urlError(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ENETUNREACH)})},
+		{name: "certificate", err: urlError(x509.UnknownAuthorityError{})},
+		{name: "dns", err: urlError(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "update.example.com", IsNotFound: true}})},
+		{name: "other", err: errors.New("invalid response")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isTransient(tc.err); got != tc.want {
+				t.Errorf("isTransient(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	base := 250 * time.Millisecond
+	for _, tc := range []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 0, want: base},
+		{attempt: 1, want: 2 * base},
+		{attempt: 3, want: 8 * base},
+		{attempt: 10, want: maxRetryBackoff},
+		{attempt: 100, want: maxRetryBackoff},
+	} {
+		t.Run(fmt.Sprint(tc.attempt), func(t *testing.T) {
+			if got := retryBackoff(base, tc.attempt); got != tc.want {
+				t.Errorf("retryBackoff(%s, %d) = %s, want %s", base, tc.attempt, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package updatecheck
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// buildDate returns when the running binary was built. The date provided with
+// WithBuildDate is preferred, falling back to the VCS commit time embedded by the Go toolchain.
+func buildDate(o Options) (time.Time, bool) {
+	if !o.BuildDate.IsZero() {
+		return o.BuildDate, true
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return time.Time{}, false
+	}
+	for _, s := range bi.Settings {
+		if s.Key == "vcs.time" {
+			t, err := time.Parse(time.RFC3339, s.Value)
+			return t, err == nil
+		}
+	}
+	return time.Time{}, false
+}
+
+// staleBuildMessage returns a message warning that the running binary is older than
+// the maximum build age, or an empty string if it isn't or the build date is unknown.
+func staleBuildMessage(app App, o Options, now time.Time) string {
+	if o.MaxBuildAge <= 0 {
+		return ""
+	}
+	built, ok := buildDate(o)
+	if !ok {
+		return ""
+	}
+	age := now.Sub(built)
+	if age < o.MaxBuildAge {
+		return ""
+	}
+	return fmt.Sprintf("This build of %s is %s old. Check for a newer version to get the latest fixes.", app, formatAge(age))
+}
+
+// stalenessMessage returns a notice that updates couldn't be checked for, if no check
+// has succeeded within the staleness period, or an empty string otherwise.
+// If a check has never succeeded, the period is measured from when the application was first run.
+func stalenessMessage(app App, vc versionConfig, o Options, now time.Time) string {
+	if o.StalenessPeriod <= 0 {
+		return ""
+	}
+	last := vc.LastCheck
+	if last.IsZero() {
+		last = vc.FirstSeen
+	}
+	if last.IsZero() || now.Sub(last) < o.StalenessPeriod {
+		return ""
+	}
+	return fmt.Sprintf("Couldn't check for updates to %s for %s. Your version may be out of date.", app, formatAge(now.Sub(last)))
+}
+
+// formatAge formats a duration in days or months, such as "9 months".
+func formatAge(d time.Duration) string {
+	days := int(d.Hours() / 24)
+	if days < 60 {
+		return pluralise(days, "day")
+	}
+	return pluralise(days/30, "month")
+}
+
+func pluralise(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,66 @@
+package updatecheck
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Release is an entry in the changelog of an update.
+type Release struct {
+	// Version is the version of the release, such as "v0.21.0".
+	Version string `json:"version"`
+	// Date is when the version was released, if the server provides it.
+	Date time.Time `json:"date,omitempty"`
+	// Notes are the release notes, with one change per line.
+	// Markdown list markers such as "- " are removed when the changelog is rendered.
+	Notes string `json:"notes"`
+}
+
+// changelogBetween returns the releases which are newer than currentVersion and not newer
+// than latestVersion, in the order the server sent them, with escape sequences and
+// disallowed links removed from the notes. Releases with versions which can't be parsed
+// are included, as they can't be placed.
+func changelogBetween(releases []Release, currentVersion string, latestVersion string, o Options) []Release {
+	current, currentErr := parseVersion(currentVersion)
+	latest, latestErr := parseVersion(latestVersion)
+
+	var between []Release
+	for _, r := range releases {
+		v, err := parseVersion(r.Version)
+		if err == nil && currentErr == nil && v.compare(current) <= 0 {
+			continue
+		}
+		if err == nil && latestErr == nil && v.compare(latest) > 0 {
+			continue
+		}
+		r.Version = sanitizeMessage(r.Version)
+		r.Notes = o.filterLinks(sanitizeMessage(r.Notes))
+		between = append(between, r)
+	}
+	return between
+}
+
+// renderChangelog formats the releases for the terminal, truncated to maxLines lines.
+func renderChangelog(releases []Release, maxLines int) string {
+	var lines []string
+	for _, r := range releases {
+		lines = append(lines, fmt.Sprintf("Changes in %s:", r.Version))
+		for _, note := range strings.Split(r.Notes, "\n") {
+			note = strings.TrimSpace(note)
+			note = strings.TrimSpace(strings.TrimLeft(note, "-*•"))
+			if note == "" {
+				continue
+			}
+			lines = append(lines, "  • "+note)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	if len(lines) > maxLines {
+		more := len(lines) - maxLines
+		lines = append(lines[:maxLines], "…and "+pluralise(more, "more line"))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,38 @@
+package updatecheck
+
+// Channel is a release channel which the user receives updates from.
+type Channel string
+
+const (
+	// ChannelStable only offers releases, never pre-releases.
+	ChannelStable Channel = "stable"
+	// ChannelBeta offers beta pre-releases as well as releases.
+	ChannelBeta Channel = "beta"
+	// ChannelNightly offers nightly builds.
+	ChannelNightly Channel = "nightly"
+)
+
+// allows returns true if users on the channel may be notified about the latest version.
+// Users on the stable channel are never notified about pre-releases.
+// If the version can't be parsed, the notification is allowed.
+func (c Channel) allows(latestVersion string) bool {
+	if c != ChannelStable || latestVersion == "" {
+		return true
+	}
+	latest, err := parseVersion(latestVersion)
+	if err != nil {
+		return true
+	}
+	return latest.Prerelease == ""
+}
+
+// resolveChannel returns the channel to check, remembering the channel
+// the user opted into in the version config.
+// If no channel is configured, the channel from the previous check is used.
+func resolveChannel(vc *versionConfig, c Channel) Channel {
+	if c == "" {
+		return vc.Channel
+	}
+	vc.Channel = c
+	return c
+}
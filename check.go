@@ -1,44 +1,75 @@
 package updatecheck
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
-	"os"
+	"io"
 	"runtime"
 	"runtime/debug"
+	"sort"
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/common-fate/clio"
 )
 
-// waitgroup is used to ensure that Check() has finished
-var waitgroup sync.WaitGroup
+// maxResponseSize is the maximum size of a response from the update checking endpoint.
+const maxResponseSize = 1 << 20
+
+// check is a handle to a single background update check.
+// The messages are written only by the goroutine running the check,
+// and may only be read after done has been closed.
+type check struct {
+	app  App
+	opts Options
+	done chan struct{}
+	msgs []message
+}
 
-var checks struct {
-	mu   sync.Mutex
-	msgs []string
+// message is a message to display to the user.
+type message struct {
+	text string
+	// latestVersion and releaseDate are shown as extra detail
+	// when the host application is verbose, if the server provides them.
+	latestVersion string
+	releaseDate   time.Time
+	// verified is true if the message came from a signed response.
+	verified bool
+	// severity is how important the message is.
+	severity Severity
+	// health is the server's advice about the stability of the latest version.
+	health ReleaseHealth
+	// expires is when the message stops being shown again on skipped checks, if the server set it.
+	expires time.Time
+	// actions are optional actions for the message.
+	actions []Action
+	// update is true if the message is for an available update, rather than an advisory.
+	update bool
+	// required is true if the server requires the running version to be upgraded.
+	required bool
+	// changelog is the release notes for the versions since the running version.
+	changelog []Release
+	// upgradeHint tells the user how to upgrade, such as "To upgrade, run: brew upgrade granted".
+	upgradeHint string
 }
 
-type checkRequest struct {
-	// Application is the app we are checking for updates to.
-	Application App `json:"application"`
-	// Version is the current version.
-	Version string `json:"version"`
-	// Architecture is the operating system's architecture.
-	Architecture string `json:"arch"`
-	// OS is the operating system.
-	OS string `json:"os"`
+// pending holds the checkers used by the package-level Check(), in the order they were started.
+var pending struct {
+	mu       sync.Mutex
+	checkers []*Checker
 }
 
-type checkResponse struct {
-	// UpdateRequired is true if there is a new version available
-	UpdateRequired bool `json:"updateRequired"`
-	// Message to display to the user. Can include security notifications.
-	Message string `json:"message"`
+// register adds a checker to the pending list, replacing any earlier checker
+// for the same application so that its messages are not printed twice.
+func register(c *Checker) {
+	pending.mu.Lock()
+	defer pending.mu.Unlock()
+	for i, existing := range pending.checkers {
+		if existing.app == c.app {
+			pending.checkers[i] = c
+			return
+		}
+	}
+	pending.checkers = append(pending.checkers, c)
 }
 
 // Check for updates to the CLI application.
@@ -46,130 +77,533 @@ type checkResponse struct {
 // to print the update message.
 //
 // 'prod' should be true if the build is a production build.
+//
+// Users can tune how often checks run by setting <APP>_UPDATE_CHECK
+// (such as GRANTED_CLI_UPDATE_CHECK) or GRANTED_UPDATE_CHECK to
+// "always", "daily" or "never". Setting <APP>_DISABLE_UPDATE_CHECK
+// to a truthy value, or calling Disable(), turns checks off.
 func Check(app App, currentVersion string, prod bool, opts ...func(*Options)) {
-	o := Options{
-		Client: http.DefaultClient,
-		URL:    "https://update-dev.commonfate.io/check",
+	CheckContext(context.Background(), app, currentVersion, prod, opts...)
+}
+
+// CheckContext is like Check, but the background check is abandoned if ctx
+// is cancelled or its deadline passes, so that Print() returns promptly
+// when the calling CLI exits early.
+// If the options are invalid, a warning is logged and no check runs.
+func CheckContext(ctx context.Context, app App, currentVersion string, prod bool, opts ...func(*Options)) {
+	if !prod {
+		opts = append([]func(*Options){WithDevelopment()}, opts...)
 	}
+	c, err := New(app, currentVersion, opts...)
+	if err != nil {
+		warn(loggerFor(opts...), "skipping update check as the options are invalid: %s", err.Error())
+		return
+	}
+	register(c)
+	c.CheckContext(ctx)
+}
 
-	if prod {
-		o.URL = "https://update.commonfate.io/check"
+// Print whether any updates are required.
+func Print() {
+	for _, l := range pendingLines() {
+		printMessage(l.text, l.severity, l.opts)
 	}
+}
 
-	for _, opt := range opts {
-		opt(&o)
+// Fprint writes any update messages to w rather than printing them with clio,
+// so that the calling CLI can control where the notice appears in its output.
+func Fprint(w io.Writer) {
+	for _, l := range pendingLines() {
+		fprintMessage(w, l.text, l.severity, l.opts)
 	}
+}
 
-	if os.Getenv("GRANTED_DISABLE_UPDATE_CHECK") == "true" {
-		clio.Debug("GRANTED_DISABLE_UPDATE_CHECK env var is true, skipping update check")
-		return
+// line is a message ready to display, along with the options of the check which produced it.
+type line struct {
+	app      App
+	text     string
+	severity Severity
+	// rank is the severity of the message which the line belongs to, so that lines
+	// such as the changelog are ordered along with their message.
+	rank Severity
+	opts Options
+}
+
+// pendingLines waits for the checks started by Check() to finish and returns their
+// messages ordered by severity, most important first, and then by application name,
+// so that security advisories lead and output is stable across runs.
+// Identical messages from different applications are only returned once.
+func pendingLines() []line {
+	var lines []line
+	for _, c := range pendingCheckers() {
+		lines = append(lines, c.lines()...)
 	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		ri, rj := severityRanks[lines[i].rank.normalize()], severityRanks[lines[j].rank.normalize()]
+		if ri != rj {
+			return ri > rj
+		}
+		return lines[i].app < lines[j].app
+	})
 
-	vc, ok := loadVersionConfig(app)
-	if ok && time.Now().Weekday() == vc.LastCheckForUpdates {
-		clio.Debug("skipping update check until tomorrow, versionconfig=%s", vc.Path())
-		return
+	seen := map[string]bool{}
+	deduped := lines[:0]
+	for _, l := range lines {
+		if seen[l.text] {
+			continue
+		}
+		seen[l.text] = true
+		deduped = append(deduped, l)
 	}
+	return deduped
+}
 
-	// reset any existing messages
-	checks.mu.Lock()
-	defer checks.mu.Unlock()
-	checks.msgs = nil
+// pendingCheckers returns a snapshot of the checkers started by Check().
+func pendingCheckers() []*Checker {
+	pending.mu.Lock()
+	defer pending.mu.Unlock()
+	checkers := make([]*Checker, len(pending.checkers))
+	copy(checkers, pending.checkers)
+	return checkers
+}
 
-	waitgroup.Add(1)
-	go doCheck(app, currentVersion, prod, vc, o)
+// lines waits for the check to finish and returns the messages to display.
+func (c *check) lines() []line {
+	<-c.done
+	// never print when invoked by a machine, as stray output would break the protocol.
+	if isMachineInvocation(c.opts) {
+		return nil
+	}
+	verbosity := c.opts.verbosity()
+	if verbosity < c.opts.MinimumVerbosity {
+		return nil
+	}
+	lines := c.messageLines(verbosity)
+	if c.opts.SingleLine && len(lines) > 1 {
+		return []line{joinLines(lines)}
+	}
+	return lines
 }
 
-// Print whether any updates are required.
-func Print() {
-	waitgroup.Wait()
-	for _, msg := range checks.msgs {
-		if msg != "" {
-			clio.Info(msg)
+// joinLines combines the lines of a check into one, for single line mode.
+// The line takes the highest severity and rank of the lines.
+func joinLines(lines []line) line {
+	joined := lines[0]
+	texts := []string{joined.text}
+	for _, l := range lines[1:] {
+		texts = append(texts, l.text)
+		if severityRanks[l.severity.normalize()] > severityRanks[joined.severity.normalize()] {
+			joined.severity = l.severity
+		}
+		if severityRanks[l.rank.normalize()] > severityRanks[joined.rank.normalize()] {
+			joined.rank = l.rank
+		}
+	}
+	joined.text = strings.Join(texts, "\n")
+	return joined
+}
+
+// messageLines returns a line for each message of the check, and for its upgrade hint,
+// changelog and other details.
+func (c *check) messageLines(verbosity Verbosity) []line {
+	var lines []line
+	var rank Severity
+	add := func(text string, severity Severity) {
+		lines = append(lines, line{app: c.app, text: text, severity: severity, rank: rank, opts: c.opts})
+	}
+	if c.opts.Trailer {
+		if trailer := c.opts.trailer(c.msgs); trailer != "" {
+			rank = SeverityUpdate
+			add(trailer, SeverityUpdate)
+		}
+		// security advisories are always shown in full.
+		for _, msg := range c.msgs {
+			if msg.severity == SeverityCritical && msg.text != "" {
+				rank = msg.severity
+				add(msg.text, msg.severity)
+			}
+		}
+		return lines
+	}
+	for _, msg := range c.msgs {
+		rank = msg.severity
+		if msg.text != "" {
+			text := msg.text
+			if msg.verified {
+				text = verifiedIndicator + text
+			}
+			add(text, msg.severity)
+		}
+		if msg.text != "" && msg.upgradeHint != "" {
+			add(msg.upgradeHint, msg.severity)
+		}
+		if c.opts.ChangelogLines > 0 && msg.update {
+			if changelog := renderChangelog(msg.changelog, c.opts.ChangelogLines); changelog != "" {
+				add(changelog, SeverityInfo)
+			}
+		}
+		if msg.health == ReleaseStabilizing && msg.latestVersion != "" {
+			add(fmt.Sprintf("%s is a new release which is still stabilizing, you may want to wait before upgrading.", msg.latestVersion), SeverityInfo)
+		}
+		if verbosity >= VerbosityVerbose && msg.latestVersion != "" {
+			add(msg.detail(), SeverityInfo)
 		}
 	}
+	return lines
 }
 
-func doCheck(app App, currentVersion string, prod bool, vc versionConfig, o Options) {
-	defer waitgroup.Done()
-	clio.Debug("checking for update, url=%s versionconfig=%s", o.URL, vc.Path())
-	r, err := callCheckAPI(app, currentVersion, prod, o)
+// doCheck runs in the background, so that loading the version config
+// from disk doesn't add latency to the calling CLI command.
+func doCheck(ctx context.Context, c *check, currentVersion string, o Options) {
+	defer close(c.done)
+
+	var timings Timings
+	start := time.Now()
+	defer func() {
+		timings.Total = time.Since(start)
+		reportTimings(timings, o)
+	}()
+
+	if po, err := applyPolicy(o); err != nil {
+		o.log().Debugf("ignoring update policy %s: %s", o.PolicyPath, err.Error())
+	} else {
+		o = po
+	}
+	if o.frequency == frequencyNever {
+		decide(c.app, o, DecisionSkippedPolicy, "update checks are disabled by policy "+o.PolicyPath, false)
+		return
+	}
+
+	if !o.MinimalTelemetry && minimalTelemetryFromEnv(c.app) {
+		o.MinimalTelemetry = true
+	}
+	recordConfig(c.app, o)
+
+	vc, ok := loadVersionConfig(c.app, o.log())
+	timings.StateLoad = time.Since(start)
+	if vc.Disabled {
+		decide(c.app, o, DecisionSkippedOptOut, "the user opted out of update checks, versionconfig="+vc.Path(), false)
+		return
+	}
+	o.Channel = resolveChannel(&vc, o.Channel)
+	o.resolveEndpoints(vc)
+	o.clientID = resolveClientID(&vc, o)
+	// the stored responses are for the version which was running during the last check.
+	if vc.Version != currentVersion {
+		vc.Responses = nil
+	}
+	o.conditional = vc.Responses
+
+	wall := o.now()
+	if clockIsImplausible(wall) {
+		o.log().Debugf("system clock appears to be wrong: %s", wall.Format(time.RFC3339))
+	}
+	now, discarded := correctClock(&vc, wall, o.ClockSkew)
+	if discarded {
+		o.log().Debugf("discarded version config timestamps which are in the future, the system clock may have changed")
+	}
+	// warn about old builds and checks which haven't succeeded for a while using only local information.
+	upToDate := ok && vc.Version == currentVersion && !vc.UpdateRequired
+	succeeded := false
+	defer func() {
+		if !succeeded {
+			if msg := stalenessMessage(c.app, vc, o, now); msg != "" {
+				c.msgs = append(c.msgs, message{text: msg})
+			}
+		}
+		if upToDate {
+			return
+		}
+		if msg := staleBuildMessage(c.app, o, now); msg != "" {
+			c.msgs = append(c.msgs, message{text: msg})
+		}
+		if !succeeded {
+			if msg := snapshotMessage(c.app, o, now); msg != "" {
+				c.msgs = append(c.msgs, message{text: msg})
+			}
+		}
+	}()
+
+	if vc.FirstSeen.IsZero() {
+		vc.FirstSeen = now
+		if o.FirstCheckDelay > 0 || o.StalenessPeriod > 0 {
+			// save now so that the first check delay and staleness period are measured from the first run.
+			if err := vc.Save(); err != nil {
+				o.log().Debugf("error saving version config: %s", err.Error())
+			}
+		}
+	}
+
+	if o.frequency != frequencyAlways && vc.StoppedVersion != "" && vc.StoppedVersion == currentVersion {
+		decide(c.app, o, DecisionSkippedStopped, "the update service told "+currentVersion+" to stop checking for updates, versionconfig="+vc.Path(), false)
+		c.msgs = vc.cachedMessages(currentVersion, now)
+		return
+	}
+
+	throttleStart := time.Now()
+	if o.frequency != frequencyAlways && now.Before(vc.SnoozedUntil) {
+		decide(c.app, o, DecisionSkippedThrottle, "the user snoozed update messages until "+vc.SnoozedUntil.Format(time.RFC3339), false)
+		// security advisories can't be snoozed.
+		for _, msg := range vc.cachedMessages(currentVersion, now) {
+			if msg.severity == SeverityCritical {
+				c.msgs = append(c.msgs, msg)
+			}
+		}
+		return
+	}
+	reason := skipReason(c.app, vc, o, now)
+	timings.Throttle = time.Since(throttleStart)
+	if reason != "" {
+		decide(c.app, o, DecisionSkippedThrottle, reason+", versionconfig="+vc.Path(), false)
+		// show the messages from the last check, so that important notices aren't lost.
+		c.msgs = vc.cachedMessages(currentVersion, now)
+		return
+	}
+
+	if o.NetworkAllowed != nil && !o.NetworkAllowed() {
+		decide(c.app, o, DecisionSkippedNetworkNotAllowed, "network access is not allowed by the host application", false)
+		return
+	}
+
+	if isOffline() {
+		decide(c.app, o, DecisionSkippedOffline, "no network connection detected", false)
+		return
+	}
+
+	o.log().Debugf("checking for update, url=%s versionconfig=%s", o.URL, vc.Path())
+	responses, err := callEndpoints(ctx, c.app, currentVersion, o, &timings)
+	if ctx.Err() != nil {
+		decide(c.app, o, DecisionCancelled, "update check was cancelled: "+ctx.Err().Error(), false)
+		return
+	}
+	if isNetworkUnreachable(err) {
+		decide(c.app, o, DecisionSkippedOffline, "network is unreachable", false)
+		return
+	}
+
+	if o.CompareLocally {
+		for i := range responses {
+			responses[i] = compareLocally(o.log(), c.app, currentVersion, responses[i])
+		}
+	}
+
+	// an update is required if any of the endpoints says so.
+	r := &Response{}
+	var serverTime time.Time
+	verified := len(o.ResponsePublicKey) > 0 && len(responses) > 0
+	for _, res := range responses {
+		verified = verified && res.verified
+		r.UpdateRequired = r.UpdateRequired || res.UpdateRequired
+		if r.LatestVersion == "" {
+			r.LatestVersion = res.LatestVersion
+		}
+		if serverTime.IsZero() {
+			serverTime = res.serverTime
+		}
+	}
+
+	oc := outcome(vc, currentVersion, r, err)
+	ttu, _ := vc.timeToUpgrade(currentVersion, now)
+	if rerr := reportOutcome(ctx, c.app, currentVersion, oc, ttu, o); rerr != nil {
+		o.log().Debugf("error reporting check outcome to collector: %s", rerr.Error())
+	}
+
 	if err != nil {
-		clio.Debug("error when checking for updates: %s", err.Error())
+		decide(c.app, o, DecisionFailed, "error when checking for updates: "+err.Error(), false)
+		vc.addHistory(HistoryEntry{Time: now, Version: currentVersion, Decision: DecisionFailed})
+		if serr := vc.Save(); serr != nil {
+			o.log().Debugf("error saving version config: %s", serr.Error())
+		}
 		return
 	}
-	vc.LastCheckForUpdates = time.Now().Weekday()
+	recordProcessCheck(c.app, o.now())
+	vc.ClockOffset = serverClockOffset(serverTime, wall, o.ClockSkew)
+	if vc.ClockOffset != 0 {
+		o.log().Debugf("system clock differs from the update server's clock by %s", vc.ClockOffset)
+		if clockIsImplausible(wall) {
+			now = wall.Add(vc.ClockOffset)
+		}
+	}
+	vc.LastCheckForUpdates = now.Weekday()
+	vc.LastCheck = now
+	vc.NextCheck = o.throttle().Next(now)
+	vc.Version = currentVersion
+	vc.UpdateRequired = r.UpdateRequired
+	vc.addHistory(HistoryEntry{
+		Time:           now,
+		Version:        currentVersion,
+		LatestVersion:  r.LatestVersion,
+		UpdateRequired: r.UpdateRequired,
+		Decision:       DecisionPerformed,
+		Verified:       verified,
+	})
+	recordEndpointMoves(&vc, responses, o)
+	vc.recordConditionalResponses(responses)
+	vc.StoppedVersion = ""
+	if stopChecking(responses, o) {
+		o.log().Debugf("the update service told %s to stop checking for updates", currentVersion)
+		vc.StoppedVersion = currentVersion
+	}
+	c.msgs = messagesFrom(responses, vc, currentVersion, o)
+	vc.cacheMessages(c.msgs, now)
 	err = vc.Save()
 	if err != nil {
-		clio.Debug("error saving version config: %s", err.Error())
+		o.log().Debugf("error saving version config: %s", err.Error())
 		// don't return here, keep going so that we can print a message anyway.
 	}
-	clio.Debugf("update required: %v, message: %v", r.UpdateRequired, r.Message)
+	decide(c.app, o, DecisionPerformed, fmt.Sprintf("update required: %v", r.UpdateRequired), r.UpdateRequired)
+	upToDate = !r.UpdateRequired
+	succeeded = true
 
-	checks.mu.Lock()
-	defer checks.mu.Unlock()
-	checks.msgs = append(checks.msgs, r.Message)
 }
 
-func callCheckAPI(app App, currentVersion string, prod bool, o Options) (*checkResponse, error) {
-	cr := checkRequest{
-		Application:  app,
-		Version:      currentVersion,
-		Architecture: runtime.GOARCH,
-		OS:           runtime.GOOS,
-	}
+// messagesFrom returns the messages to display from the responses, removing escape sequences
+// and filtering out updates which the user has ignored or which are excluded by the options.
+func messagesFrom(responses []Response, vc versionConfig, currentVersion string, o Options) []message {
+	var msgs []message
+	// messages are deduplicated by ID, so that an advisory sent by several endpoints is only shown once.
+	seen := map[string]bool{}
+	signing := len(o.ResponsePublicKey) > 0
+	for _, r := range responses {
+		// the message is displayed in the user's terminal, so escape sequences from the server are removed.
+		r.Message = o.filterLinks(sanitizeMessage(r.Message))
+		r.LatestVersion = sanitizeMessage(r.LatestVersion)
+		o.log().Debugf("update required: %v, message: %v", r.UpdateRequired, r.Message)
 
-	b := new(bytes.Buffer)
-	err := json.NewEncoder(b).Encode(cr)
-	if err != nil {
-		return nil, err
-	}
+		// required updates can't be ignored.
+		if r.LatestVersion != "" && vc.isIgnored(r.LatestVersion) && !r.Required {
+			o.log().Debugf("not showing update to %s as the user has ignored this version", r.LatestVersion)
+			continue
+		}
 
-	req, _ := http.NewRequest("POST", o.URL, b)
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("User-Agent", userAgent())
+		if !o.UpdatePolicy.allows(o.log(), currentVersion, r.LatestVersion) {
+			o.log().Debugf("not showing update to %s as it is excluded by the update policy %q", r.LatestVersion, o.UpdatePolicy)
+			continue
+		}
 
-	res, err := o.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
+		if signing && !r.verified {
+			if r.Critical {
+				o.log().Debugf("not showing critical message as the response is not signed: %s", r.Message)
+				continue
+			}
+			if r.Required {
+				o.log().Debugf("ignoring required update as the response is not signed: %s", r.Message)
+				r.Required = false
+			}
+			r.Message = withoutIndicator(r.Message)
+		}
+
+		if !o.Channel.allows(r.LatestVersion) {
+			o.log().Debugf("not showing update to %s as it is a pre-release and the channel is %q", r.LatestVersion, o.Channel)
+			continue
+		}
 
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("got invalid response from update checker API: %d", res.StatusCode)
+		id := r.MessageID
+		if id == "" {
+			id = r.Message
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		var hint string
+		if r.UpdateRequired {
+			r.Message, hint = withUpgradeCommand(r.Message, o.upgradeCommand())
+		}
+		msgs = append(msgs, message{
+			text:          r.Message,
+			update:        r.UpdateRequired,
+			required:      r.Required,
+			changelog:     changelogBetween(r.Changelog, currentVersion, r.LatestVersion, o),
+			upgradeHint:   hint,
+			latestVersion: r.LatestVersion,
+			releaseDate:   r.ReleaseDate,
+			verified:      r.verified,
+			severity:      r.severity(),
+			health:        r.Health,
+			expires:       r.ExpiresAt,
+			actions:       actionsFrom(r.Actions, o),
+		})
 	}
 
-	var resp checkResponse
-	err = json.NewDecoder(res.Body).Decode(&resp)
-	if err != nil {
-		return nil, err
+	// notices are shown even if the update in the same response is filtered out,
+	// so that security advisories aren't lost when the user ignores a version.
+	for _, r := range responses {
+		for _, n := range r.Messages {
+			n.Message = o.filterLinks(sanitizeMessage(n.Message))
+			if signing && !r.verified {
+				if n.Severity == SeverityCritical {
+					o.log().Debugf("not showing critical message as the response is not signed: %s", n.Message)
+					continue
+				}
+				n.Message = withoutIndicator(n.Message)
+			}
+			id := n.ID
+			if id == "" {
+				id = n.Message
+			}
+			if n.Message == "" || seen[id] {
+				continue
+			}
+			seen[id] = true
+			msgs = append(msgs, message{
+				text:     n.Message,
+				verified: r.verified,
+				severity: n.Severity.normalize(),
+				expires:  r.ExpiresAt,
+			})
+		}
 	}
+	return msgs
+}
 
-	return &resp, nil
+// userAgent returns a header to use in User-Agent.
+// The format is "cf-updatecheck-go/<library version> <calling package> (<os>)"
+// and is part of the wire format, so it must remain stable.
+func userAgent(o Options) string {
+	return fmt.Sprintf("cf-updatecheck-go/%s %s (%s)", getLibraryVersion(), o.caller, runtime.GOOS)
 }
 
-// userAgent returns a header to use in User-Agent
-func userAgent() string {
-	return fmt.Sprintf("cf-updatecheck-go/%s %s (%s)", getLibraryVersion(), retrieveCallInfo(), runtime.GOOS)
+// minimalUserAgent returns a header to use in User-Agent when minimal telemetry is enabled,
+// which omits the calling package and OS. The format is "cf-updatecheck-go/<library version>".
+func minimalUserAgent() string {
+	return "cf-updatecheck-go/" + getLibraryVersion()
 }
 
-// retrieveCallInfo finds the Go package that the update was called from to include in the user agent header.
-func retrieveCallInfo() string {
-	pc, _, _, ok := runtime.Caller(3)
-	if !ok {
-		return ""
-	}
-	parts := strings.Split(runtime.FuncForPC(pc).Name(), ".")
-	pl := len(parts)
-	packageName := ""
+// libraryPath is the import path of this module. Its packages are skipped when finding the caller.
+const libraryPath = "github.com/common-fate/updatecheck"
 
-	if parts[pl-2][0] == '(' {
-		packageName = strings.Join(parts[0:pl-2], ".")
-	} else {
-		packageName = strings.Join(parts[0:pl-1], ".")
+// callerPackage finds the Go package which is using the library, to include in the user agent header.
+// It walks the stack rather than using a fixed depth, so it must be called before any goroutine starts.
+// Frames in this module are skipped, except for tests.
+func callerPackage() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		pkg := packageName(f.Function)
+		inLibrary := pkg == libraryPath || strings.HasPrefix(pkg, libraryPath+"/")
+		if pkg != "" && pkg != "runtime" && (!inLibrary || strings.HasSuffix(f.File, "_test.go")) {
+			return pkg
+		}
+		if !more {
+			return ""
+		}
 	}
+}
 
-	return packageName
+// packageName returns the package of a function name reported by the runtime,
+// such as "github.com/common-fate/granted/pkg/assume.(*Assumer).Run".
+func packageName(function string) string {
+	slash := strings.LastIndex(function, "/")
+	dot := strings.Index(function[slash+1:], ".")
+	if dot < 0 {
+		return ""
+	}
+	return function[:slash+1+dot]
 }
+
 func getLibraryVersion() (libver string) {
 	bi, ok := debug.ReadBuildInfo()
 	if !ok {
@@ -0,0 +1,176 @@
+package updatecheck
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testApp is a second application, for tests which check several applications at once.
+const testApp App = "test-cli"
+
+// updateServer returns a server which reports that latestVersion is available.
+func updateServer(t *testing.T, latestVersion string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(Response{
+			UpdateRequired: true,
+			LatestVersion:  latestVersion,
+			Message:        "A new version is available: " + latestVersion,
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// resetPending clears the checkers started by the package-level Check for the rest of the test.
+func resetPending(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		pending.mu.Lock()
+		pending.checkers = nil
+		pending.mu.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestOverlappingChecksSameApp(t *testing.T) {
+	isolateState(t)
+	resetPending(t)
+	srv := updateServer(t, "v0.21.0")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Check(GrantedCLI, "v0.20.0", true, testOptions(srv.URL)...)
+			Print()
+			Fprint(io.Discard)
+		}()
+	}
+	wg.Wait()
+
+	var buf strings.Builder
+	Fprint(&buf)
+	if got := strings.Count(buf.String(), "v0.21.0"); got != 1 {
+		t.Errorf("update message printed %d times, want once:\n%s", got, buf.String())
+	}
+}
+
+func TestOverlappingChecksDifferentApps(t *testing.T) {
+	isolateState(t)
+	resetPending(t)
+	granted := updateServer(t, "v0.21.0")
+	cf := updateServer(t, "v1.5.0")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			Check(GrantedCLI, "v0.20.0", true, testOptions(granted.URL)...)
+			Fprint(io.Discard)
+		}()
+		go func() {
+			defer wg.Done()
+			Check(testApp, "v1.4.0", true, testOptions(cf.URL)...)
+			Print()
+		}()
+	}
+	wg.Wait()
+
+	var buf strings.Builder
+	Fprint(&buf)
+	for _, want := range []string{"v0.21.0", "v1.5.0"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output is missing %s:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestCheckerOverlappingChecks(t *testing.T) {
+	isolateState(t)
+	srv := updateServer(t, "v0.21.0")
+	c, err := New(GrantedCLI, "v0.20.0", testOptions(srv.URL)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Check()
+			c.Fprint(io.Discard)
+			c.Print()
+		}()
+	}
+	wg.Wait()
+
+	var buf strings.Builder
+	c.Fprint(&buf)
+	if !strings.Contains(buf.String(), "v0.21.0") {
+		t.Errorf("output is missing the update message:\n%s", buf.String())
+	}
+}
+
+// finishedChecker returns a checker for app whose check has finished with msgs.
+func finishedChecker(t *testing.T, app App, msgs ...message) *Checker {
+	t.Helper()
+	o, err := NewOptions(true, WithLogger(DiscardLogger), WithInteractive(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &check{app: app, opts: o, done: make(chan struct{}), msgs: msgs}
+	close(h.done)
+	return &Checker{app: app, opts: o, current: h}
+}
+
+func TestPendingLinesOrder(t *testing.T) {
+	resetPending(t)
+	register(finishedChecker(t, testApp,
+		message{text: "test-cli v2 is available", severity: SeverityUpdate, latestVersion: "v2", health: ReleaseStabilizing},
+		message{text: "test-cli advisory", severity: SeverityCritical},
+	))
+	register(finishedChecker(t, GrantedCLI,
+		message{text: "granted v0.21.0 is available", severity: SeverityUpdate},
+		message{text: "granted is deprecated", severity: SeverityWarning},
+		message{text: "test-cli advisory", severity: SeverityCritical},
+	))
+
+	var got []string
+	for _, l := range pendingLines() {
+		got = append(got, l.text)
+	}
+	want := []string{
+		"test-cli advisory",
+		"granted is deprecated",
+		"granted v0.21.0 is available",
+		"test-cli v2 is available",
+		"v2 is a new release which is still stabilizing, you may want to wait before upgrading.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got lines\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestCheckInvalidOptions(t *testing.T) {
+	resetPending(t)
+	var log recordingLogger
+	Check(GrantedCLI, "v0.20.0", true, WithLogger(&log), func(o *Options) { o.URL = "not a url" })
+
+	if len(log.warn) != 1 || !strings.Contains(log.warn[0], "URL") {
+		t.Errorf("expected a warning about the invalid URL, got %q", log.warn)
+	}
+	if len(pendingCheckers()) != 0 {
+		t.Error("a check was started with invalid options")
+	}
+}
@@ -0,0 +1,94 @@
+package updatecheck
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// Checker checks for updates to a single application.
+// Unlike the package-level Check() and Print(), each Checker holds its own state,
+// so several independent update checks can run in one process, such as in a CLI
+// which wraps two Common Fate tools.
+type Checker struct {
+	app            App
+	currentVersion string
+	opts           Options
+
+	mu      sync.Mutex
+	current *check
+}
+
+// New returns a Checker for app, which is currently running currentVersion.
+// Checks use the production update checking endpoint, unless a URL or
+// WithDevelopment() is provided. If the options are invalid an *OptionError is returned.
+func New(app App, currentVersion string, opts ...func(*Options)) (*Checker, error) {
+	o, err := NewOptions(true, opts...)
+	if err != nil {
+		return nil, err
+	}
+	c := Checker{
+		app:            app,
+		currentVersion: currentVersion,
+		opts:           o,
+	}
+	return &c, nil
+}
+
+// Check for updates in the background. Call Print() to print the update message.
+func (c *Checker) Check() {
+	c.CheckContext(context.Background())
+}
+
+// CheckContext is like Check, but the background check is abandoned
+// if ctx is cancelled or its deadline passes.
+func (c *Checker) CheckContext(ctx context.Context) {
+	o := c.opts
+	if isMachineInvocation(o) {
+		decide(c.app, o, DecisionSkippedMachineInvocation, "invoked for shell completion or as a credential process", false)
+		return
+	}
+
+	freq, envVar := frequencyFromEnv(c.app, o.DisableEnvVar)
+	if freq == frequencyNever {
+		decide(c.app, o, DecisionSkippedEnvVar, envVar+" env var disables update checks", false)
+		return
+	}
+	o.frequency = freq
+	// an explicit request to always check takes precedence over detection.
+	if reason := o.nonInteractiveReason(); reason != "" && freq != frequencyAlways {
+		decide(c.app, o, DecisionSkippedNonInteractive, reason, false)
+		return
+	}
+
+	h := &check{app: c.app, opts: o, done: make(chan struct{})}
+	c.mu.Lock()
+	c.current = h
+	c.mu.Unlock()
+	go doCheck(ctx, h, c.currentVersion, o)
+}
+
+// Print waits for the most recent check to finish and prints whether any updates are required.
+func (c *Checker) Print() {
+	for _, l := range c.lines() {
+		printMessage(l.text, l.severity, l.opts)
+	}
+}
+
+// Fprint is like Print, but writes the update messages to w without colours.
+func (c *Checker) Fprint(w io.Writer) {
+	for _, l := range c.lines() {
+		fprintMessage(w, l.text, l.severity, l.opts)
+	}
+}
+
+// lines returns the messages from the most recent check.
+func (c *Checker) lines() []line {
+	c.mu.Lock()
+	h := c.current
+	c.mu.Unlock()
+	if h == nil {
+		return nil
+	}
+	return h.lines()
+}
@@ -0,0 +1,90 @@
+package updatecheck
+
+import (
+	"testing"
+	"time"
+)
+
+// throttledCheckAllocs is the allocation budget for a check which is throttled,
+// from starting the check to its result being ready. This is the cost of most
+// invocations of the calling CLI, including credential_process flows.
+const throttledCheckAllocs = 60
+
+// throttledChecker returns a Checker whose checks are throttled by a recent check.
+func throttledChecker(t testing.TB) *Checker {
+	t.Helper()
+	isolateState(t)
+	recentlyChecked(t, GrantedCLI, "v0.20.0", time.Now())
+	c, err := New(GrantedCLI, "v0.20.0", WithInteractive(true), WithLogger(DiscardLogger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// wait waits for the most recent check to finish.
+func (c *Checker) wait() {
+	c.mu.Lock()
+	h := c.current
+	c.mu.Unlock()
+	<-h.done
+}
+
+func TestThrottledCheckAllocs(t *testing.T) {
+	c := throttledChecker(t)
+	allocs := testing.AllocsPerRun(100, func() {
+		c.Check()
+		c.wait()
+	})
+	if allocs > throttledCheckAllocs {
+		t.Errorf("a throttled check made %v allocations, the budget is %d", allocs, throttledCheckAllocs)
+	}
+}
+
+// BenchmarkCheck measures the synchronous part of Check(), which the calling CLI waits for.
+func BenchmarkCheck(b *testing.B) {
+	c := throttledChecker(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Check()
+		b.StopTimer()
+		c.wait()
+		b.StartTimer()
+	}
+}
+
+// BenchmarkThrottledCheck measures a throttled check from start to finish,
+// including loading the state and deciding to skip the check in the background.
+func BenchmarkThrottledCheck(b *testing.B) {
+	c := throttledChecker(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Check()
+		c.wait()
+	}
+}
+
+func TestNewEndpoint(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []func(*Options)
+		want string
+	}{
+		{name: "production by default", want: prodURL},
+		{name: "development", opts: []func(*Options){WithDevelopment()}, want: devURL},
+		{name: "URL", opts: []func(*Options){WithDevelopment(), func(o *Options) { o.URL = "https://update.example.com/check" }}, want: "https://update.example.com/check"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(GrantedCLI, "v0.20.0", tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.opts.URL != tt.want {
+				t.Errorf("URL = %q, want %q", c.opts.URL, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package updatecheck
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"hash/fnv"
+)
+
+// newClientID returns a random anonymous client ID.
+// It isn't derived from anything about the user or machine.
+func newClientID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// cohort returns a stable bucket between 0 and 99 for the client ID,
+// which the server can use to experiment with message phrasing.
+func cohort(clientID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(clientID))
+	return int(h.Sum32() % 100)
+}
+
+// resolveClientID returns the anonymous client ID stored in the version config,
+// generating one if needed. It returns an empty string unless the client ID is
+// enabled and the user has consented to telemetry.
+func resolveClientID(vc *versionConfig, o Options) string {
+	if !o.AnonymousClientID || !o.telemetryAllowed() {
+		return ""
+	}
+	if vc.ClientID == "" {
+		id, err := newClientID()
+		if err != nil {
+			o.log().Debugf("error generating anonymous client ID: %s", err.Error())
+			return ""
+		}
+		vc.ClientID = id
+	}
+	return vc.ClientID
+}
@@ -0,0 +1,78 @@
+package updatecheck
+
+import (
+	"sync"
+	"time"
+)
+
+// minPlausibleTime is the earliest time which the system clock could plausibly be set to.
+// Earlier times indicate a broken clock, such as a machine with a flat RTC battery reporting 1970.
+var minPlausibleTime = time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// clockIsImplausible returns true if the system clock is grossly wrong.
+func clockIsImplausible(now time.Time) bool {
+	return now.Before(minPlausibleTime)
+}
+
+// processChecks records when each app was last checked by this process.
+// Unless a clock is set with WithClock, the times include a monotonic clock reading,
+// so that the throttle is unaffected by changes to the system clock.
+var processChecks struct {
+	mu   sync.Mutex
+	last map[App]time.Time
+}
+
+// recordProcessCheck records that app has been checked by this process at now.
+func recordProcessCheck(app App, now time.Time) {
+	processChecks.mu.Lock()
+	defer processChecks.mu.Unlock()
+	if processChecks.last == nil {
+		processChecks.last = map[App]time.Time{}
+	}
+	processChecks.last[app] = now
+}
+
+// lastProcessCheck returns when app was last checked by this process,
+// and false if it hasn't been checked.
+func lastProcessCheck(app App) (time.Time, bool) {
+	processChecks.mu.Lock()
+	defer processChecks.mu.Unlock()
+	t, ok := processChecks.last[app]
+	return t, ok
+}
+
+// correctClock returns the time to use for throttling decisions. If the system clock is
+// grossly wrong, it is corrected using the offset to the server's clock measured during
+// the last check. Timestamps in the version config which are further in the future than the
+// skew tolerance, which happens when the clock has moved backwards, are discarded so that
+// checks aren't suppressed indefinitely. It returns true if any timestamps were discarded.
+func correctClock(vc *versionConfig, now time.Time, skew time.Duration) (time.Time, bool) {
+	if clockIsImplausible(now) && vc.ClockOffset != 0 {
+		now = now.Add(vc.ClockOffset)
+	}
+
+	discarded := false
+	if vc.LastCheck.After(now.Add(skew)) {
+		vc.LastCheck = time.Time{}
+		discarded = true
+	}
+	if vc.FirstSeen.After(now.Add(skew)) {
+		vc.FirstSeen = now
+		discarded = true
+	}
+	return now, discarded
+}
+
+// serverClockOffset returns the difference between the server's clock, from the
+// Date header of its response, and the local clock. It returns zero if the server
+// didn't send a Date header or the difference is within the skew tolerance.
+func serverClockOffset(serverTime, localTime time.Time, skew time.Duration) time.Duration {
+	if serverTime.IsZero() {
+		return 0
+	}
+	offset := serverTime.Sub(localTime)
+	if offset < skew && offset > -skew {
+		return 0
+	}
+	return offset
+}
@@ -0,0 +1,105 @@
+package updatecheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Outcome is the final result of an update check, reported to the collector.
+type Outcome string
+
+const (
+	// OutcomeUpToDate means that no update was required.
+	OutcomeUpToDate Outcome = "up-to-date"
+	// OutcomeUpdateAvailable means that an update was found.
+	OutcomeUpdateAvailable Outcome = "update-available"
+	// OutcomeUpdated means that the user upgraded after a previous check found an update.
+	OutcomeUpdated Outcome = "updated"
+	// OutcomeIgnored means that a previous check found an update,
+	// but the user is still running the same version.
+	OutcomeIgnored Outcome = "ignored"
+	// OutcomeFailed means that the update check failed.
+	OutcomeFailed Outcome = "failed"
+)
+
+// collectorRequest is the body sent to the collector URL.
+type collectorRequest struct {
+	Application App     `json:"application"`
+	Version     string  `json:"version"`
+	Outcome     Outcome `json:"outcome"`
+	// TimeToUpgradeSeconds is how long the user ran an outdated version before upgrading.
+	// It is only sent if enabled with WithTimeToUpgrade().
+	TimeToUpgradeSeconds int64 `json:"timeToUpgradeSeconds,omitempty"`
+}
+
+// outcome determines the outcome of a check, by comparing the response
+// against the version and result of the previous check stored in the version config.
+func outcome(prev versionConfig, currentVersion string, r *Response, err error) Outcome {
+	if err != nil {
+		return OutcomeFailed
+	}
+	if prev.UpdateRequired && prev.Version != "" && prev.Version != currentVersion {
+		return OutcomeUpdated
+	}
+	if !r.UpdateRequired {
+		return OutcomeUpToDate
+	}
+	if prev.UpdateRequired && prev.Version == currentVersion {
+		return OutcomeIgnored
+	}
+	return OutcomeUpdateAvailable
+}
+
+// reportOutcome sends the outcome of the check to the collector URL, if one is configured.
+// timeToUpgrade is only reported if the outcome is OutcomeUpdated and WithTimeToUpgrade() is enabled.
+func reportOutcome(ctx context.Context, app App, currentVersion string, oc Outcome, timeToUpgrade time.Duration, o Options) error {
+	if o.CollectorURL == "" || !o.telemetryAllowed() {
+		return nil
+	}
+	// the outcome is reported before the check finishes, so it mustn't delay Print().
+	ctx, cancel := context.WithTimeout(ctx, o.Timeout)
+	defer cancel()
+
+	cr := collectorRequest{
+		Application: app,
+		Version:     currentVersion,
+		Outcome:     oc,
+	}
+	if oc == OutcomeUpdated && o.ReportTimeToUpgrade {
+		cr.TimeToUpgradeSeconds = int64(timeToUpgrade / time.Second)
+	}
+	b := new(bytes.Buffer)
+	err := json.NewEncoder(b).Encode(cr)
+	if err != nil {
+		return err
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, "POST", o.CollectorURL, b)
+	req.Header.Add("Content-Type", "application/json")
+	if o.MinimalTelemetry {
+		req.Header.Add("User-Agent", minimalUserAgent())
+	} else {
+		req.Header.Add("User-Agent", userAgent(o))
+	}
+
+	release, err := acquireRequestSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	res, err := o.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("got invalid response from collector: %d", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package updatecheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReportOutcomeTimeout(t *testing.T) {
+	hang := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hang
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(hang) })
+
+	o, err := NewOptions(true, WithCollector(srv.URL), WithTimeout(50*time.Millisecond), WithLogger(DiscardLogger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	err = reportOutcome(context.Background(), testApp, "v0.20.0", OutcomeUpToDate, 0, o)
+	if err == nil {
+		t.Fatal("expected an error from a collector which doesn't respond")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("reportOutcome took %s, want it to give up after the timeout", elapsed)
+	}
+}
@@ -0,0 +1,47 @@
+package updatecheck
+
+import "net/http"
+
+// The update check is a POST, and RFC 9110 requires a server to answer a failed If-None-Match
+// or If-Modified-Since on a POST with 412 Precondition Failed rather than 304 Not Modified.
+// This deliberately deviates from the standard validators: the stored validators are sent in
+// custom headers instead, which proxies don't evaluate, and the update service answers with
+// 304 Not Modified if the response hasn't changed.
+const (
+	ifNoneMatchHeader     = "X-Updatecheck-If-None-Match"
+	ifModifiedSinceHeader = "X-Updatecheck-If-Modified-Since"
+)
+
+// notModified returns true if the status code means that the stored response can be reused.
+func notModified(status int) bool {
+	return status == http.StatusNotModified
+}
+
+// conditionalResponse is the last response from an endpoint, stored in the version config
+// so that later checks can make conditional requests, and reuse the response if the
+// endpoint replies that it hasn't changed.
+type conditionalResponse struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	// Body is the response body. A signed response is signed again by the update service
+	// when it is reused, as the signature includes the request's nonce.
+	Body string `json:"body"`
+}
+
+// recordConditionalResponses stores the responses which have an ETag or Last-Modified header,
+// so that the next check of each endpoint sends If-None-Match or If-Modified-Since.
+func (vc *versionConfig) recordConditionalResponses(responses []Response) {
+	for _, r := range responses {
+		if r.endpoint == "" {
+			continue
+		}
+		if r.conditional.ETag == "" && r.conditional.LastModified == "" {
+			delete(vc.Responses, r.endpoint)
+			continue
+		}
+		if vc.Responses == nil {
+			vc.Responses = map[string]conditionalResponse{}
+		}
+		vc.Responses[r.endpoint] = r.conditional
+	}
+}
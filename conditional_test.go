@@ -0,0 +1,64 @@
+package updatecheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	isolateState(t)
+
+	var mu sync.Mutex
+	var validators []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		validators = append(validators, r.Header.Get(ifNoneMatchHeader))
+		mu.Unlock()
+		if r.Header.Get("If-None-Match") != "" {
+			t.Errorf("unexpected If-None-Match header on a POST")
+		}
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get(ifNoneMatchHeader) == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(`{"updateRequired":true,"message":"update to v0.21.0"}`))
+	}))
+	defer srv.Close()
+	opts := testOptions(srv.URL)
+
+	for i, tc := range []struct {
+		version   string
+		validator string
+	}{
+		{version: "v0.20.0", validator: ""},
+		{version: "v0.20.0", validator: `"v1"`},
+		// the stored response is discarded when the version changes.
+		{version: "v0.20.1", validator: ""},
+	} {
+		got := runCheck(t, GrantedCLI, tc.version, opts...)
+		if got != "update to v0.21.0\n" {
+			t.Errorf("check %d: got message %q", i, got)
+		}
+		mu.Lock()
+		if validators[i] != tc.validator {
+			t.Errorf("check %d: got validator %q, want %q", i, validators[i], tc.validator)
+		}
+		mu.Unlock()
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	for status, want := range map[int]bool{
+		http.StatusOK:                  false,
+		http.StatusNotModified:         true,
+		http.StatusPreconditionFailed:  false,
+		http.StatusInternalServerError: false,
+	} {
+		if got := notModified(status); got != want {
+			t.Errorf("notModified(%d) = %v, want %v", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package updatecheck
+
+import (
+	"os"
+	"sync"
+)
+
+// ConsentProvider gives updatecheck access to the host application's existing
+// telemetry consent state, so that users don't need to opt in or out twice.
+type ConsentProvider interface {
+	// TelemetryConsent returns true if the user has consented to telemetry.
+	TelemetryConsent() bool
+}
+
+// minimalTelemetryFromEnv returns true if <APP>_UPDATE_CHECK_MINIMAL or GRANTED_UPDATE_CHECK_MINIMAL
+// is set to a truthy value, which has the same effect as WithMinimalTelemetry().
+func minimalTelemetryFromEnv(app App) bool {
+	return isTruthy(os.Getenv(envPrefix(app)+"_UPDATE_CHECK_MINIMAL")) || isTruthy(os.Getenv("GRANTED_UPDATE_CHECK_MINIMAL"))
+}
+
+// doNotTrackLogged ensures that DO_NOT_TRACK is only logged once per process,
+// as telemetryAllowed is called for each kind of optional telemetry.
+var doNotTrackLogged sync.Once
+
+// telemetryAllowed returns true if optional telemetry, such as the OS version
+// and collector reports, may be sent. It is never sent with minimal telemetry.
+// Setting the de-facto standard DO_NOT_TRACK env var to a truthy value always disables it.
+// If no ConsentProvider is configured, the individual telemetry options are the only opt-in.
+func (o Options) telemetryAllowed() bool {
+	if o.MinimalTelemetry {
+		return false
+	}
+	if isTruthy(os.Getenv("DO_NOT_TRACK")) {
+		doNotTrackLogged.Do(func() {
+			o.log().Debugf("DO_NOT_TRACK is set, optional telemetry will not be sent")
+		})
+		return false
+	}
+	if o.ConsentProvider == nil {
+		return true
+	}
+	if !o.ConsentProvider.TelemetryConsent() {
+		o.log().Debugf("telemetry consent has not been given, optional telemetry will not be sent")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package updatecheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestMinimalTelemetry(t *testing.T) {
+	isolateState(t)
+
+	var mu sync.Mutex
+	var validators []string
+	collected := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/collect" {
+			collected++
+			return
+		}
+		validators = append(validators, r.Header.Get(ifNoneMatchHeader))
+		w.Header().Set("ETag", `"client-123"`)
+		w.Write([]byte(`{"updateRequired":true,"message":"update"}`))
+	}))
+	defer srv.Close()
+
+	opts := testOptions(srv.URL, WithCollector(srv.URL+"/collect"), WithMinimalTelemetry())
+	runCheck(t, GrantedCLI, "v0.20.0", opts...)
+	runCheck(t, GrantedCLI, "v0.20.0", opts...)
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i, v := range validators {
+		if v != "" {
+			t.Errorf("check %d sent validator %q", i, v)
+		}
+	}
+	if collected != 0 {
+		t.Errorf("sent %d collector reports", collected)
+	}
+}
+
+func TestDoNotTrackLoggedOnce(t *testing.T) {
+	t.Setenv("DO_NOT_TRACK", "1")
+	doNotTrackLogged = sync.Once{}
+	var log recordingLogger
+	o := Options{Logger: &log}
+
+	for i := 0; i < 3; i++ {
+		if o.telemetryAllowed() {
+			t.Fatal("telemetry is allowed with DO_NOT_TRACK set")
+		}
+	}
+	if len(log.debug) != 1 {
+		t.Errorf("logged %d times, want once: %q", len(log.debug), log.debug)
+	}
+}
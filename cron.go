@@ -0,0 +1,166 @@
+package updatecheck
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron returns a Throttle which allows a check once each time the cron expression
+// comes around, such as "0 9 * * MON" to align update checks with a team's weekly
+// maintenance window. The expression has the standard five fields (minute, hour,
+// day of month, month and day of week) and is evaluated in local time.
+// Checks run on the first invocation after each scheduled time. Times which are skipped
+// when the clocks go forward for daylight saving don't match.
+func Cron(expr string) (Throttle, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields, got %d", expr, len(fields))
+	}
+	var c cronThrottle
+	var err error
+	specs := []struct {
+		field    *uint64
+		min, max int
+		names    []string
+	}{
+		{&c.minute, 0, 59, nil},
+		{&c.hour, 0, 23, nil},
+		{&c.dom, 1, 31, nil},
+		{&c.month, 1, 12, []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+		{&c.dow, 0, 6, []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+	}
+	for i, s := range specs {
+		*s.field, err = parseCronField(fields[i], s.min, s.max, s.names)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+	}
+	c.domAny = fields[2] == "*"
+	c.dowAny = fields[4] == "*"
+	return c, nil
+}
+
+// cronThrottle is a parsed cron expression. Each field is a bitmask of the allowed values.
+type cronThrottle struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny record whether the day fields were unrestricted,
+	// as a day matches if either restricted field matches.
+	domAny, dowAny bool
+}
+
+func (c cronThrottle) Next(last time.Time) time.Time {
+	if last.IsZero() {
+		return last
+	}
+	t := last.Local().Truncate(time.Minute).Add(time.Minute)
+	// every valid expression matches within a few years, so give up after five.
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = advance(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location()))
+		case !c.dayMatches(t):
+			t = advance(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location()))
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = advance(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location()))
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			// a time which is repeated when the clocks go back only matches the first time.
+			if first := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location()); !first.Equal(t) {
+				t = t.Add(time.Minute)
+				continue
+			}
+			return t
+		}
+	}
+	return neverThrottle{}.Next(last)
+}
+
+// advance returns next if it is after t. time.Date moves a time which doesn't exist because
+// the clocks went forward, such as 02:00 on the day daylight saving starts, back before t,
+// so the start of the following hour is returned instead.
+func advance(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	n := t.Add(time.Hour)
+	return time.Date(n.Year(), n.Month(), n.Day(), n.Hour(), 0, 0, 0, n.Location())
+}
+
+// dayMatches follows the usual cron rule: if both the day of month and day of week
+// are restricted, a day matches if either of them does.
+func (c cronThrottle) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if !c.domAny && !c.dowAny {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// parseCronField parses a comma-separated list of values, ranges and steps,
+// such as "1-5", "*/15" or "MON,WED,FRI", into a bitmask.
+func parseCronField(field string, min, max int, names []string) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i != -1 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rng, step = part[:i], n
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			lo, err = parseCronValue(bounds[0], min, max, names)
+			if err != nil {
+				return 0, err
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				hi, err = parseCronValue(bounds[1], min, max, names)
+				if err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// "5/15" means every 15 starting from 5.
+				hi = max
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+// parseCronValue parses a single number or name within [min, max].
+// Sunday may be written as 7 in the day of week field.
+func parseCronValue(s string, min, max int, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(s, name) {
+			return i + min, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if names != nil && min == 0 && v == 7 {
+		return 0, nil
+	}
+	if v < min || v > max {
+		return 0, fmt.Errorf("value %d is out of range %d-%d", v, min, max)
+	}
+	return v, nil
+}
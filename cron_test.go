@@ -0,0 +1,180 @@
+package updatecheck
+
+import (
+	"testing"
+	"time"
+)
+
+// setLocal evaluates cron expressions in loc for the rest of the test.
+func setLocal(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s isn't available: %s", name, err)
+	}
+	local := time.Local
+	time.Local = loc
+	t.Cleanup(func() { time.Local = local })
+	return loc
+}
+
+// bits returns a bitmask with the given values set.
+func bits(values ...int) uint64 {
+	var mask uint64
+	for _, v := range values {
+		mask |= 1 << uint(v)
+	}
+	return mask
+}
+
+func TestCronParse(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    cronThrottle
+		wantErr bool
+	}{
+		{expr: "*/15 * * * *", want: cronThrottle{minute: bits(0, 15, 30, 45)}},
+		{expr: "5/20 * * * *", want: cronThrottle{minute: bits(5, 25, 45)}},
+		{expr: "0 9-17/4 * * *", want: cronThrottle{minute: bits(0), hour: bits(9, 13, 17)}},
+		{expr: "0 9 * * MON-FRI", want: cronThrottle{minute: bits(0), hour: bits(9), dow: bits(1, 2, 3, 4, 5)}},
+		{expr: "0 9 * * mon,wed,fri", want: cronThrottle{minute: bits(0), hour: bits(9), dow: bits(1, 3, 5)}},
+		{expr: "0 9 * * 7", want: cronThrottle{minute: bits(0), hour: bits(9), dow: bits(0)}},
+		{expr: "0 0 1,15 JAN,jul *", want: cronThrottle{minute: bits(0), hour: bits(0), dom: bits(1, 15), month: bits(1, 7)}},
+		{expr: "  0  0   1 1 *  ", want: cronThrottle{minute: bits(0), hour: bits(0), dom: bits(1), month: bits(1)}},
+		{expr: "", wantErr: true},
+		{expr: "* * * *", wantErr: true},
+		{expr: "* * * * * *", wantErr: true},
+		{expr: "60 * * * *", wantErr: true},
+		{expr: "* 24 * * *", wantErr: true},
+		{expr: "* * 0 * *", wantErr: true},
+		{expr: "* * 32 * *", wantErr: true},
+		{expr: "* * * 13 *", wantErr: true},
+		{expr: "* * * * 8", wantErr: true},
+		{expr: "*/0 * * * *", wantErr: true},
+		{expr: "*/x * * * *", wantErr: true},
+		{expr: "30-10 * * * *", wantErr: true},
+		{expr: "abc * * * *", wantErr: true},
+		{expr: "1,,2 * * * *", wantErr: true},
+		{expr: "* * * FOO *", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			th, err := Cron(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Cron() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := th.(cronThrottle)
+			// unrestricted fields allow every value, which is checked by TestCronNext.
+			for _, f := range []struct {
+				name      string
+				got, want uint64
+			}{
+				{"minute", got.minute, tt.want.minute},
+				{"hour", got.hour, tt.want.hour},
+				{"day of month", got.dom, tt.want.dom},
+				{"month", got.month, tt.want.month},
+				{"day of week", got.dow, tt.want.dow},
+			} {
+				if f.want != 0 && f.got != f.want {
+					t.Errorf("%s = %b, want %b", f.name, f.got, f.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	utc := setLocal(t, "UTC")
+	date := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, utc)
+	}
+	tests := []struct {
+		name string
+		expr string
+		last time.Time
+		want time.Time
+	}{
+		{name: "every 15 minutes", expr: "*/15 * * * *", last: date(2024, 5, 1, 10, 7), want: date(2024, 5, 1, 10, 15)},
+		{name: "at the scheduled time", expr: "*/15 * * * *", last: date(2024, 5, 1, 10, 15), want: date(2024, 5, 1, 10, 30)},
+		{name: "next hour", expr: "0 * * * *", last: date(2024, 5, 1, 23, 30), want: date(2024, 5, 2, 0, 0)},
+		{name: "weekly", expr: "0 9 * * MON", last: date(2024, 5, 5, 12, 0), want: date(2024, 5, 6, 9, 0)},
+		{name: "weekdays over a weekend", expr: "0 9 * * 1-5", last: date(2024, 5, 3, 10, 0), want: date(2024, 5, 6, 9, 0)},
+		{name: "end of month", expr: "0 0 1 * *", last: date(2024, 1, 31, 12, 0), want: date(2024, 2, 1, 0, 0)},
+		{name: "skips short months", expr: "0 0 31 * *", last: date(2024, 2, 1, 0, 0), want: date(2024, 3, 31, 0, 0)},
+		{name: "end of year", expr: "0 0 1 1 *", last: date(2024, 6, 1, 0, 0), want: date(2025, 1, 1, 0, 0)},
+		{name: "leap day", expr: "0 0 29 2 *", last: date(2023, 3, 1, 0, 0), want: date(2024, 2, 29, 0, 0)},
+		{name: "day of month or day of week", expr: "0 0 13 * FRI", last: date(2024, 9, 1, 0, 0), want: date(2024, 9, 6, 0, 0)},
+		{name: "never matches", expr: "0 0 30 2 *", last: date(2024, 1, 1, 0, 0), want: neverThrottle{}.Next(date(2024, 1, 1, 0, 0))},
+		{name: "never checked", expr: "0 9 * * *", last: time.Time{}, want: time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th, err := Cron(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := th.Next(tt.last); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.last, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronNextDST(t *testing.T) {
+	ny := setLocal(t, "America/New_York")
+	// the clocks go forward from 02:00 to 03:00 on 10 March 2024,
+	// and back from 02:00 to 01:00 on 3 November 2024.
+	edt := time.FixedZone("EDT", -4*60*60)
+	est := time.FixedZone("EST", -5*60*60)
+	tests := []struct {
+		name string
+		expr string
+		last time.Time
+		want time.Time
+	}{
+		{
+			name: "skipped time",
+			expr: "30 2 * * *",
+			last: time.Date(2024, 3, 9, 3, 0, 0, 0, est),
+			want: time.Date(2024, 3, 11, 2, 30, 0, 0, edt),
+		},
+		{
+			name: "after the clocks go forward",
+			expr: "0 3 * * *",
+			last: time.Date(2024, 3, 9, 12, 0, 0, 0, est),
+			want: time.Date(2024, 3, 10, 3, 0, 0, 0, edt),
+		},
+		{
+			name: "repeated time only matches once",
+			expr: "30 1 * * *",
+			last: time.Date(2024, 11, 3, 1, 30, 0, 0, edt),
+			want: time.Date(2024, 11, 4, 1, 30, 0, 0, est),
+		},
+		{
+			name: "first of a repeated time",
+			expr: "30 1 * * *",
+			last: time.Date(2024, 11, 2, 12, 0, 0, 0, edt),
+			want: time.Date(2024, 11, 3, 1, 30, 0, 0, edt),
+		},
+		{
+			name: "after the clocks go back",
+			expr: "0 9 * * *",
+			last: time.Date(2024, 11, 2, 9, 0, 0, 0, edt),
+			want: time.Date(2024, 11, 3, 9, 0, 0, 0, est),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th, err := Cron(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := th.Next(tt.last); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.last, got.In(ny), tt.want.In(ny))
+			}
+		})
+	}
+}
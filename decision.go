@@ -0,0 +1,67 @@
+package updatecheck
+
+import "time"
+
+// Decision describes what happened when an update check was requested.
+type Decision string
+
+const (
+	// DecisionSkippedEnvVar means that the check was disabled by an environment variable.
+	DecisionSkippedEnvVar Decision = "skipped-env-var"
+	// DecisionSkippedOptOut means that the user opted out of update checks with Disable().
+	DecisionSkippedOptOut Decision = "skipped-opt-out"
+	// DecisionSkippedMachineInvocation means that the process was invoked for shell completion
+	// or as a credential process, where output would break the protocol.
+	DecisionSkippedMachineInvocation Decision = "skipped-machine-invocation"
+	// DecisionSkippedNonInteractive means that the process isn't used interactively,
+	// such as in CI, so nobody would see the message.
+	DecisionSkippedNonInteractive Decision = "skipped-non-interactive"
+	// DecisionSkippedStopped means that the update service told the running version to stop
+	// checking for updates, such as because it has reached end of life.
+	DecisionSkippedStopped Decision = "skipped-stopped"
+	// DecisionSkippedPolicy means that the check was disabled by a signed organization policy.
+	DecisionSkippedPolicy Decision = "skipped-policy"
+	// DecisionSkippedThrottle means that the check was skipped because one ran recently.
+	DecisionSkippedThrottle Decision = "skipped-throttle"
+	// DecisionSkippedNetworkNotAllowed means that the host application vetoed network access.
+	DecisionSkippedNetworkNotAllowed Decision = "skipped-network-not-allowed"
+	// DecisionSkippedOffline means that the machine appeared to be offline.
+	DecisionSkippedOffline Decision = "skipped-offline"
+	// DecisionCancelled means that the check was abandoned because its context was cancelled.
+	DecisionCancelled Decision = "cancelled"
+	// DecisionFailed means that the check was performed but failed.
+	DecisionFailed Decision = "failed"
+	// DecisionPerformed means that the check was performed successfully.
+	DecisionPerformed Decision = "performed"
+)
+
+// CheckDecision is a structured record of what happened when an update check was requested,
+// which host applications can include in their own debug output and support bundles.
+type CheckDecision struct {
+	// App is the application which was checked.
+	App App `json:"app"`
+	// Decision is what happened.
+	Decision Decision `json:"decision"`
+	// Detail is a human-readable explanation of the decision.
+	Detail string `json:"detail"`
+	// UpdateFound is true if the check was performed and an update is available.
+	UpdateFound bool `json:"updateFound"`
+	// Time is when the decision was made.
+	Time time.Time `json:"time"`
+}
+
+// decide logs the decision made for a check of app, and passes it to the decision hook if one is configured.
+func decide(app App, o Options, d Decision, detail string, updateFound bool) {
+	o.log().Debugf("update check %s: %s", d, detail)
+	cd := CheckDecision{
+		App:         app,
+		Decision:    d,
+		Detail:      detail,
+		UpdateFound: updateFound,
+		Time:        time.Now(),
+	}
+	recordDecision(cd)
+	if o.OnDecision != nil {
+		o.OnDecision(cd)
+	}
+}
@@ -0,0 +1,173 @@
+package updatecheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"time"
+)
+
+// DNS record types used in DNS-over-HTTPS queries.
+const (
+	dnsTypeA    = 1
+	dnsTypeAAAA = 28
+)
+
+// dnsNXDomain is the DNS response code for a name which doesn't exist.
+const dnsNXDomain = 3
+
+// dohResolver resolves hostnames using the JSON API for DNS-over-HTTPS
+// supported by public resolvers such as https://cloudflare-dns.com/dns-query
+// and https://dns.google/resolve.
+type dohResolver struct {
+	url    string
+	client *http.Client
+	// timeout limits the DNS-over-HTTPS lookup and the fallback lookup separately.
+	timeout time.Duration
+	// fallback is used if the DNS-over-HTTPS resolver can't be reached or fails,
+	// but not if it answers that the name doesn't exist.
+	fallback resolver
+	log      Logger
+}
+
+// dohStatusError is returned when the DNS-over-HTTPS resolver answers with an error code.
+type dohStatusError struct {
+	host   string
+	status int
+}
+
+func (e dohStatusError) Error() string {
+	return fmt.Sprintf("DNS-over-HTTPS resolver returned status %d for %s", e.status, e.host)
+}
+
+// dohResponse is the JSON response from a DNS-over-HTTPS query.
+type dohResponse struct {
+	// Status is the DNS response code. 0 means NOERROR.
+	Status int `json:"Status"`
+	Answer []struct {
+		Type int    `json:"type"`
+		Data string `json:"data"`
+	} `json:"Answer"`
+}
+
+func (r dohResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	// the system resolver reports DNS timings to httptrace itself, so do the same here.
+	trace := httptrace.ContextClientTrace(ctx)
+	if trace != nil && trace.DNSStart != nil {
+		trace.DNSStart(httptrace.DNSStartInfo{Host: host})
+	}
+	ips, err := r.lookupWithFallback(ctx, host)
+	if trace != nil && trace.DNSDone != nil {
+		trace.DNSDone(httptrace.DNSDoneInfo{Addrs: ips, Err: err})
+	}
+	return ips, err
+}
+
+// lookupWithFallback looks up host with the DNS-over-HTTPS resolver, and then with the
+// fallback resolver if that fails for any reason other than the name not existing.
+func (r dohResolver) lookupWithFallback(ctx context.Context, host string) ([]net.IPAddr, error) {
+	ips, err := r.lookupWithTimeout(ctx, host, r.lookup)
+	var se dohStatusError
+	if err == nil || r.fallback == nil || ctx.Err() != nil || (errors.As(err, &se) && se.status == dnsNXDomain) {
+		return ips, err
+	}
+	r.log.Debugf("error resolving %s with DNS-over-HTTPS, using the system resolver: %s", host, err.Error())
+	return r.lookupWithTimeout(ctx, host, r.fallback.LookupIPAddr)
+}
+
+// lookupWithTimeout calls lookup, giving up after the resolver's timeout.
+func (r dohResolver) lookupWithTimeout(ctx context.Context, host string, lookup func(context.Context, string) ([]net.IPAddr, error)) ([]net.IPAddr, error) {
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+	return lookup(ctx, host)
+}
+
+// lookup queries A and AAAA records for host concurrently.
+func (r dohResolver) lookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	type result struct {
+		ips []net.IPAddr
+		err error
+	}
+	results := make(chan result, 2)
+	for _, t := range []int{dnsTypeA, dnsTypeAAAA} {
+		go func(t int) {
+			ips, err := r.query(ctx, host, t)
+			results <- result{ips: ips, err: err}
+		}(t)
+	}
+
+	var ips []net.IPAddr
+	var err error
+	for i := 0; i < 2; i++ {
+		res := <-results
+		if res.err != nil {
+			err = res.err
+			continue
+		}
+		ips = append(ips, res.ips...)
+	}
+	// only fail if neither query returned any addresses.
+	if len(ips) == 0 && err != nil {
+		return nil, err
+	}
+	return ips, nil
+}
+
+// query looks up records of type t for host.
+func (r dohResolver) query(ctx context.Context, host string, t int) ([]net.IPAddr, error) {
+	u, err := url.Parse(r.url)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("name", host)
+	q.Set("type", fmt.Sprint(t))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/dns-json")
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got invalid response from DNS-over-HTTPS resolver: %d", res.StatusCode)
+	}
+
+	var dr dohResponse
+	err = json.NewDecoder(res.Body).Decode(&dr)
+	if err != nil {
+		return nil, err
+	}
+	if dr.Status != 0 {
+		return nil, dohStatusError{host: host, status: dr.Status}
+	}
+
+	var ips []net.IPAddr
+	for _, a := range dr.Answer {
+		// skip CNAME and other records which aren't addresses.
+		if a.Type != t {
+			continue
+		}
+		ip := net.ParseIP(a.Data)
+		if ip == nil {
+			continue
+		}
+		ips = append(ips, net.IPAddr{IP: ip})
+	}
+	return ips, nil
+}
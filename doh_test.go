@@ -0,0 +1,148 @@
+package updatecheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// resolverFunc is a resolver which calls itself.
+type resolverFunc func(ctx context.Context, host string) ([]net.IPAddr, error)
+
+func (f resolverFunc) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return f(ctx, host)
+}
+
+func TestDoHResolver(t *testing.T) {
+	systemIP := net.IPAddr{IP: net.ParseIP("192.0.2.99")}
+	tests := []struct {
+		name         string
+		handler      http.HandlerFunc
+		fallback     bool
+		want         []net.IPAddr
+		wantErr      bool
+		wantFallback bool
+	}{
+		{
+			name: "success",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("name") != "update.example.com" || r.Header.Get("Accept") != "application/dns-json" {
+					t.Errorf("unexpected query %s", r.URL)
+				}
+				switch r.URL.Query().Get("type") {
+				case "1":
+					_, _ = w.Write([]byte(`{"Status":0,"Answer":[{"type":5,"data":"cdn.example.com."},{"type":1,"data":"192.0.2.1"}]}`))
+				case "28":
+					_, _ = w.Write([]byte(`{"Status":0,"Answer":[{"type":28,"data":"2001:db8::1"}]}`))
+				}
+			},
+			fallback: true,
+			want:     []net.IPAddr{{IP: net.ParseIP("192.0.2.1")}, {IP: net.ParseIP("2001:db8::1")}},
+		},
+		{
+			name: "only IPv4",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("type") == "1" {
+					_, _ = w.Write([]byte(`{"Status":0,"Answer":[{"type":1,"data":"192.0.2.1"}]}`))
+					return
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			fallback: true,
+			want:     []net.IPAddr{{IP: net.ParseIP("192.0.2.1")}},
+		},
+		{
+			name: "NXDOMAIN",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"Status":3}`))
+			},
+			fallback: true,
+			wantErr:  true,
+		},
+		{
+			name: "server error falls back",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			fallback:     true,
+			want:         []net.IPAddr{systemIP},
+			wantFallback: true,
+		},
+		{
+			name: "SERVFAIL falls back",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"Status":2}`))
+			},
+			fallback:     true,
+			want:         []net.IPAddr{systemIP},
+			wantFallback: true,
+		},
+		{
+			name: "timeout falls back",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+			},
+			fallback:     true,
+			want:         []net.IPAddr{systemIP},
+			wantFallback: true,
+		},
+		{
+			name: "no fallback",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewTLSServer(tt.handler)
+			t.Cleanup(srv.Close)
+
+			var fellBack atomic.Bool
+			r := dohResolver{url: srv.URL, client: srv.Client(), timeout: 100 * time.Millisecond, log: DiscardLogger}
+			if tt.fallback {
+				r.fallback = resolverFunc(func(ctx context.Context, host string) ([]net.IPAddr, error) {
+					fellBack.Store(true)
+					if _, ok := ctx.Deadline(); !ok {
+						t.Error("the fallback lookup has no deadline")
+					}
+					return []net.IPAddr{systemIP}, nil
+				})
+			}
+			got, err := r.LookupIPAddr(context.Background(), "update.example.com")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LookupIPAddr() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if fellBack.Load() != tt.wantFallback {
+				t.Errorf("fell back to the system resolver = %v, want %v", fellBack.Load(), tt.wantFallback)
+			}
+			// A and AAAA records are looked up concurrently, so they may be in either order.
+			if len(got) == 2 && got[0].IP.To4() == nil {
+				got[0], got[1] = got[1], got[0]
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LookupIPAddr() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDoHResolverNXDomainError(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"Status":3}`))
+	}))
+	t.Cleanup(srv.Close)
+	r := dohResolver{url: srv.URL, client: srv.Client(), log: DiscardLogger}
+	_, err := r.LookupIPAddr(context.Background(), "missing.example.com")
+	var se dohStatusError
+	if !errors.As(err, &se) || se.status != dnsNXDomain {
+		t.Errorf("LookupIPAddr() error = %v, want NXDOMAIN", err)
+	}
+}
@@ -0,0 +1,103 @@
+package updatecheck
+
+import (
+	"net/http"
+	"strings"
+)
+
+// maxEndpointMoves limits how many stored endpoint migrations are followed,
+// in case they form a loop.
+const maxEndpointMoves = 5
+
+// movedEndpoint returns the endpoint which the response asks future checks to use
+// instead of the endpoint which was called, or an empty string if it hasn't moved.
+// A newEndpoint field takes precedence over a permanent redirect.
+func (r Response) movedEndpoint() string {
+	if r.NewEndpoint != "" {
+		return r.NewEndpoint
+	}
+	return r.redirectedTo
+}
+
+// resolveEndpoint returns the endpoint to call in place of u,
+// following the migrations stored in the version config.
+func (vc versionConfig) resolveEndpoint(u string) string {
+	for i := 0; i < maxEndpointMoves; i++ {
+		next, ok := vc.Endpoints[u]
+		if !ok || next == u {
+			break
+		}
+		u = next
+	}
+	return u
+}
+
+// resolveEndpoints replaces the configured URLs with the endpoints they have migrated to.
+// Endpoints set by a signed policy are never replaced.
+func (o *Options) resolveEndpoints(vc versionConfig) {
+	resolve := func(u string) string {
+		if o.pinned[u] {
+			return u
+		}
+		return vc.resolveEndpoint(u)
+	}
+	o.URL = resolve(o.URL)
+	var additional []string
+	for _, u := range o.AdditionalURLs {
+		additional = append(additional, resolve(u))
+	}
+	o.AdditionalURLs = additional
+}
+
+// permanentlyRedirected returns the URL which res was permanently redirected to,
+// or an empty string if it wasn't redirected or any hop in the redirect chain was temporary.
+func permanentlyRedirected(res *http.Response) string {
+	if res.Request == nil || res.Request.Response == nil {
+		return ""
+	}
+	for req := res.Request; req.Response != nil; req = req.Response.Request {
+		if req.Response.StatusCode != http.StatusPermanentRedirect {
+			return ""
+		}
+		if req.Response.Request == nil {
+			break
+		}
+	}
+	return res.Request.URL.String()
+}
+
+// recordEndpointMoves stores the endpoint migrations from the responses in the version config,
+// so that future checks call the new endpoints. Only https endpoints can move, and only to
+// https endpoints, so that an on-path attacker can't redirect future checks. Endpoints set by
+// a signed policy can't move, and if response signing is enabled, only signed responses can
+// move an endpoint.
+func recordEndpointMoves(vc *versionConfig, responses []Response, o Options) {
+	signing := len(o.ResponsePublicKey) > 0
+	for _, r := range responses {
+		moved := r.movedEndpoint()
+		if moved == "" || r.endpoint == "" || moved == r.endpoint {
+			continue
+		}
+		if o.pinned[r.endpoint] {
+			o.log().Debugf("ignoring move of %s to %s as the endpoint is set by policy", r.endpoint, moved)
+			continue
+		}
+		if !strings.HasPrefix(r.endpoint, "https://") {
+			o.log().Debugf("ignoring move of %s to %s as the endpoint doesn't use https", r.endpoint, moved)
+			continue
+		}
+		if signing && !r.verified {
+			o.log().Debugf("ignoring move of %s to %s as the response is not signed", r.endpoint, moved)
+			continue
+		}
+		if err := validateURL(moved, true); err != nil {
+			o.log().Debugf("ignoring move of %s to an invalid endpoint: %s", r.endpoint, err.Error())
+			continue
+		}
+		o.log().Debugf("update checking endpoint %s has moved to %s", r.endpoint, moved)
+		if vc.Endpoints == nil {
+			vc.Endpoints = map[string]string{}
+		}
+		vc.Endpoints[r.endpoint] = moved
+	}
+}
@@ -0,0 +1,80 @@
+package updatecheck
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestRecordEndpointMoves(t *testing.T) {
+	o := Options{Logger: DiscardLogger, pinned: map[string]bool{"https://pinned.example.com": true}}
+	for _, tc := range []struct {
+		name     string
+		endpoint string
+		moved    string
+		want     bool
+	}{
+		{name: "https to https", endpoint: "https://old.example.com", moved: "https://new.example.com", want: true},
+		{name: "https to http", endpoint: "https://old.example.com", moved: "http://new.example.com"},
+		{name: "http endpoint", endpoint: "http://old.example.com", moved: "https://new.example.com"},
+		{name: "pinned by policy", endpoint: "https://pinned.example.com", moved: "https://new.example.com"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var vc versionConfig
+			recordEndpointMoves(&vc, []Response{{endpoint: tc.endpoint, NewEndpoint: tc.moved}}, o)
+			_, got := vc.Endpoints[tc.endpoint]
+			if got != tc.want {
+				t.Errorf("recorded move = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestResolveEndpointsIgnoresPinned(t *testing.T) {
+	vc := versionConfig{Endpoints: map[string]string{
+		"https://pinned.example.com": "https://new.example.com",
+		"https://old.example.com":    "https://new.example.com",
+	}}
+	o := Options{
+		URL:            "https://pinned.example.com",
+		AdditionalURLs: []string{"https://old.example.com"},
+		pinned:         map[string]bool{"https://pinned.example.com": true},
+	}
+	o.resolveEndpoints(vc)
+	if o.URL != "https://pinned.example.com" {
+		t.Errorf("pinned URL was moved to %s", o.URL)
+	}
+	if o.AdditionalURLs[0] != "https://new.example.com" {
+		t.Errorf("additional URL was not moved: %s", o.AdditionalURLs[0])
+	}
+}
+
+// redirectChain returns a response which followed redirects with the status codes, in order.
+func redirectChain(codes ...int) *http.Response {
+	req := &http.Request{URL: &url.URL{Scheme: "https", Host: "hop0.example.com"}}
+	for i, code := range codes {
+		prev := &http.Response{StatusCode: code, Request: req}
+		req = &http.Request{URL: &url.URL{Scheme: "https", Host: "hop" + string(rune('1'+i)) + ".example.com"}, Response: prev}
+	}
+	return &http.Response{StatusCode: http.StatusOK, Request: req}
+}
+
+func TestPermanentlyRedirected(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		codes []int
+		want  string
+	}{
+		{name: "not redirected", want: ""},
+		{name: "permanent", codes: []int{308}, want: "https://hop1.example.com"},
+		{name: "all permanent", codes: []int{308, 308}, want: "https://hop2.example.com"},
+		{name: "temporary then permanent", codes: []int{307, 308}, want: ""},
+		{name: "permanent then temporary", codes: []int{308, 302}, want: ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := permanentlyRedirected(redirectChain(tc.codes...)); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
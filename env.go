@@ -0,0 +1,83 @@
+package updatecheck
+
+import (
+	"os"
+	"strings"
+	"unicode"
+)
+
+// frequency is how often update checks run, as configured by environment variables.
+type frequency string
+
+const (
+	// frequencyDaily checks at most once per check interval. This is the default.
+	frequencyDaily frequency = "daily"
+	// frequencyAlways checks on every invocation, ignoring throttling.
+	frequencyAlways frequency = "always"
+	// frequencyNever disables update checks.
+	frequencyNever frequency = "never"
+)
+
+// envPrefix returns the environment variable prefix for app,
+// such as "GRANTED_CLI" for "granted-cli".
+func envPrefix(app App) string {
+	return strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return '_'
+		}
+		return unicode.ToUpper(r)
+	}, string(app))
+}
+
+// isTruthy returns true if v is a common truthy value such as "true", "1", "yes" or "on".
+func isTruthy(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "1", "true", "yes", "y", "on":
+		return true
+	}
+	return false
+}
+
+// isFalsy returns true if v is a common falsy value such as "false", "0", "no" or "off".
+func isFalsy(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "0", "false", "no", "n", "off":
+		return true
+	}
+	return false
+}
+
+// frequencyFromEnv reads the check frequency for app from the environment,
+// returning the frequency and the variable it was read from.
+//
+// <APP>_UPDATE_CHECK takes precedence over GRANTED_UPDATE_CHECK, which applies to all apps.
+// Either can be set to "always", "daily" or "never", or to a truthy or falsy value
+// to enable or disable checks. <APP>_DISABLE_UPDATE_CHECK, disableEnvVar if it is set,
+// GRANTED_DISABLE_UPDATE_CHECK or the de-facto standard NO_UPDATE_NOTIFIER set to a
+// truthy value also disable checks.
+func frequencyFromEnv(app App, disableEnvVar string) (frequency, string) {
+	for _, name := range []string{envPrefix(app) + "_UPDATE_CHECK", "GRANTED_UPDATE_CHECK"} {
+		v := strings.ToLower(strings.TrimSpace(os.Getenv(name)))
+		switch {
+		case v == "":
+			continue
+		case v == string(frequencyAlways):
+			return frequencyAlways, name
+		case v == string(frequencyNever) || isFalsy(v):
+			return frequencyNever, name
+		case v == string(frequencyDaily) || isTruthy(v):
+			return frequencyDaily, name
+		}
+	}
+
+	names := []string{envPrefix(app) + "_DISABLE_UPDATE_CHECK", "GRANTED_DISABLE_UPDATE_CHECK", "NO_UPDATE_NOTIFIER"}
+	if disableEnvVar != "" {
+		names = append([]string{disableEnvVar}, names...)
+	}
+	for _, name := range names {
+		if isTruthy(os.Getenv(name)) {
+			return frequencyNever, name
+		}
+	}
+	return frequencyDaily, ""
+}
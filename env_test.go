@@ -0,0 +1,87 @@
+package updatecheck
+
+import "testing"
+
+func TestFrequencyFromEnv(t *testing.T) {
+	tests := []struct {
+		name          string
+		env           map[string]string
+		disableEnvVar string
+		want          frequency
+		wantVar       string
+	}{
+		{name: "default", want: frequencyDaily},
+		{name: "app always", env: map[string]string{"GRANTED_CLI_UPDATE_CHECK": "always"}, want: frequencyAlways, wantVar: "GRANTED_CLI_UPDATE_CHECK"},
+		{name: "app never", env: map[string]string{"GRANTED_CLI_UPDATE_CHECK": "never"}, want: frequencyNever, wantVar: "GRANTED_CLI_UPDATE_CHECK"},
+		{name: "app daily", env: map[string]string{"GRANTED_CLI_UPDATE_CHECK": "daily"}, want: frequencyDaily, wantVar: "GRANTED_CLI_UPDATE_CHECK"},
+		{name: "case and whitespace", env: map[string]string{"GRANTED_CLI_UPDATE_CHECK": " Always "}, want: frequencyAlways, wantVar: "GRANTED_CLI_UPDATE_CHECK"},
+		{name: "app falsy", env: map[string]string{"GRANTED_CLI_UPDATE_CHECK": "off"}, want: frequencyNever, wantVar: "GRANTED_CLI_UPDATE_CHECK"},
+		{name: "app truthy", env: map[string]string{"GRANTED_CLI_UPDATE_CHECK": "1"}, want: frequencyDaily, wantVar: "GRANTED_CLI_UPDATE_CHECK"},
+		{name: "global", env: map[string]string{"GRANTED_UPDATE_CHECK": "never"}, want: frequencyNever, wantVar: "GRANTED_UPDATE_CHECK"},
+		{
+			name:    "app takes precedence over global",
+			env:     map[string]string{"GRANTED_CLI_UPDATE_CHECK": "always", "GRANTED_UPDATE_CHECK": "never"},
+			want:    frequencyAlways,
+			wantVar: "GRANTED_CLI_UPDATE_CHECK",
+		},
+		{
+			name:    "other app ignored",
+			env:     map[string]string{"ASSUME_UPDATE_CHECK": "never", "GRANTED_UPDATE_CHECK": "always"},
+			want:    frequencyAlways,
+			wantVar: "GRANTED_UPDATE_CHECK",
+		},
+		{
+			name:    "invalid app value falls back to global",
+			env:     map[string]string{"GRANTED_CLI_UPDATE_CHECK": "sometimes", "GRANTED_UPDATE_CHECK": "never"},
+			want:    frequencyNever,
+			wantVar: "GRANTED_UPDATE_CHECK",
+		},
+		{name: "invalid values ignored", env: map[string]string{"GRANTED_CLI_UPDATE_CHECK": "sometimes", "GRANTED_UPDATE_CHECK": "weekly"}, want: frequencyDaily},
+		{name: "app disable", env: map[string]string{"GRANTED_CLI_DISABLE_UPDATE_CHECK": "true"}, want: frequencyNever, wantVar: "GRANTED_CLI_DISABLE_UPDATE_CHECK"},
+		{name: "global disable", env: map[string]string{"GRANTED_DISABLE_UPDATE_CHECK": "yes"}, want: frequencyNever, wantVar: "GRANTED_DISABLE_UPDATE_CHECK"},
+		{name: "NO_UPDATE_NOTIFIER", env: map[string]string{"NO_UPDATE_NOTIFIER": "1"}, want: frequencyNever, wantVar: "NO_UPDATE_NOTIFIER"},
+		{name: "falsy disable ignored", env: map[string]string{"GRANTED_CLI_DISABLE_UPDATE_CHECK": "false", "NO_UPDATE_NOTIFIER": "0"}, want: frequencyDaily},
+		{name: "invalid disable ignored", env: map[string]string{"GRANTED_CLI_DISABLE_UPDATE_CHECK": "please"}, want: frequencyDaily},
+		{
+			name:          "custom disable variable",
+			env:           map[string]string{"MYTOOL_NO_UPDATE_CHECK": "on", "NO_UPDATE_NOTIFIER": "1"},
+			disableEnvVar: "MYTOOL_NO_UPDATE_CHECK",
+			want:          frequencyNever,
+			wantVar:       "MYTOOL_NO_UPDATE_CHECK",
+		},
+		{
+			name:    "frequency takes precedence over disable",
+			env:     map[string]string{"GRANTED_CLI_UPDATE_CHECK": "always", "GRANTED_CLI_DISABLE_UPDATE_CHECK": "true"},
+			want:    frequencyAlways,
+			wantVar: "GRANTED_CLI_UPDATE_CHECK",
+		},
+	}
+	vars := []string{
+		"GRANTED_CLI_UPDATE_CHECK", "GRANTED_UPDATE_CHECK", "ASSUME_UPDATE_CHECK",
+		"GRANTED_CLI_DISABLE_UPDATE_CHECK", "GRANTED_DISABLE_UPDATE_CHECK", "NO_UPDATE_NOTIFIER", "MYTOOL_NO_UPDATE_CHECK",
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, v := range vars {
+				t.Setenv(v, tt.env[v])
+			}
+			got, gotVar := frequencyFromEnv(GrantedCLI, tt.disableEnvVar)
+			if got != tt.want || gotVar != tt.wantVar {
+				t.Errorf("frequencyFromEnv() = %s, %q, want %s, %q", got, gotVar, tt.want, tt.wantVar)
+			}
+		})
+	}
+}
+
+func TestEnvPrefix(t *testing.T) {
+	for app, want := range map[App]string{
+		GrantedCLI:  "GRANTED_CLI",
+		"my.tool":   "MY_TOOL",
+		"tool2":     "TOOL2",
+		"outil-été": "OUTIL__T_",
+	} {
+		if got := envPrefix(app); got != want {
+			t.Errorf("envPrefix(%q) = %q, want %q", app, got, want)
+		}
+	}
+}
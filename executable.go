@@ -0,0 +1,62 @@
+package updatecheck
+
+import (
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+)
+
+// AppFromExecutable returns the application name derived from the running executable,
+// such as "granted" for /usr/local/bin/granted or granted.exe. It returns an empty
+// string if the executable path can't be determined.
+func AppFromExecutable() App {
+	exe, err := os.Executable()
+	if err != nil {
+		if len(os.Args) == 0 {
+			return ""
+		}
+		exe = os.Args[0]
+	}
+	return appFromPath(exe)
+}
+
+// appFromPath returns the application name for the executable at path.
+func appFromPath(path string) App {
+	name := filepath.Base(path)
+	if strings.EqualFold(filepath.Ext(name), ".exe") {
+		name = name[:len(name)-len(".exe")]
+	}
+	if name == "." || name == string(filepath.Separator) {
+		return ""
+	}
+	return App(name)
+}
+
+// VersionFromBuildInfo returns the version of the main module embedded by the Go toolchain,
+// such as "v0.21.0" for binaries installed with 'go install'. It returns an empty string for
+// development builds, where the toolchain reports the version as "(devel)".
+func VersionFromBuildInfo() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	if bi.Main.Version == "" || bi.Main.Version == "(devel)" {
+		return ""
+	}
+	return bi.Main.Version
+}
+
+// CheckAuto checks for updates using the application name and version derived from the running
+// executable with AppFromExecutable() and VersionFromBuildInfo(), so that small tools
+// don't need to pass them explicitly. The check is skipped if either can't be determined.
+// Call Print() to print the update message.
+func CheckAuto(prod bool, opts ...func(*Options)) {
+	app := AppFromExecutable()
+	version := VersionFromBuildInfo()
+	if app == "" || version == "" {
+		loggerFor(opts...).Debugf("skipping update check: couldn't determine the application name and version from the executable")
+		return
+	}
+	Check(app, version, prod, opts...)
+}
@@ -0,0 +1,117 @@
+package updatecheck
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+func FuzzParseVersion(f *testing.F) {
+	for _, seed := range [][2]string{
+		{"v1.2.3", "1.2.4"},
+		{"v1.0.0-alpha", "v1.0.0-alpha.1"},
+		{"1.0.0-beta.11", "1.0.0-beta.2"},
+		{"v2", "v2.0.0+build.5"},
+		{"1.0.0-01", "1.0.0-1"},
+		{"", "v"},
+	} {
+		f.Add(seed[0], seed[1])
+	}
+	f.Fuzz(func(t *testing.T, a, b string) {
+		va, errA := parseVersion(a)
+		vb, errB := parseVersion(b)
+		if errA != nil || errB != nil {
+			return
+		}
+		if c := va.compare(va); c != 0 {
+			t.Errorf("%q compared with itself = %d", a, c)
+		}
+		if ab, ba := va.compare(vb), vb.compare(va); ab != -ba {
+			t.Errorf("compare(%q, %q) = %d but compare(%q, %q) = %d", a, b, ab, b, a, ba)
+		}
+	})
+}
+
+// checkSanitized fails the test if msg could mis-render in a terminal.
+func checkSanitized(t *testing.T, msg string) {
+	t.Helper()
+	if !utf8.ValidString(msg) {
+		return
+	}
+	for _, r := range msg {
+		if r != '\n' && r != '\t' && unicode.IsControl(r) {
+			t.Fatalf("sanitized message contains control character %U: %q", r, msg)
+		}
+	}
+	if n := utf8.RuneCountInString(msg); n > maxMessageLength+len("...") {
+		t.Fatalf("sanitized message has %d characters", n)
+	}
+}
+
+func FuzzSanitizeMessage(f *testing.F) {
+	for _, seed := range []string{
+		"A new version is available",
+		"\x1b[31mred\x1b[0m",
+		"\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\",
+		"\u009b2J\u009d0;title\u009c",
+		"tab\tand\nnewline\r\x07",
+		strings.Repeat("x", maxMessageLength+10),
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, msg string) {
+		checkSanitized(t, sanitizeMessage(msg))
+	})
+}
+
+// roundTripFunc is an http.RoundTripper implemented by a function.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func FuzzResponseDecoding(f *testing.F) {
+	for _, seed := range []string{
+		`{"updateRequired":true,"message":"update","latestVersion":"v0.21.0"}`,
+		`{"message":"\u001b[2Jcleared","messages":[{"severity":"critical","message":"CVE"}]}`,
+		`{"changelog":[{"version":"v1","notes":"- a\n- b"}],"actions":[{"type":"open-url","url":"https://example.com"}]}`,
+		`{"updateRequired":"yes"}`,
+		`null`,
+		`{`,
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, body []byte) {
+		o, err := NewOptions(true, WithLogger(DiscardLogger), func(o *Options) {
+			o.Client = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{},
+					Body:       io.NopCloser(bytes.NewReader(body)),
+					Request:    r,
+				}, nil
+			})}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := httpBackend{url: o.URL, opts: o}
+		resp, err := h.call(context.Background(), Request{Application: GrantedCLI, Version: "v0.20.0"}, []byte(`{}`), "test")
+		if err != nil {
+			return
+		}
+		resp = compareLocally(DiscardLogger, GrantedCLI, "v0.20.0", resp)
+		c := &check{app: GrantedCLI, opts: o, done: make(chan struct{}), msgs: messagesFrom([]Response{resp}, versionConfig{}, "v0.20.0", o)}
+		close(c.done)
+		for _, l := range c.lines() {
+			for _, line := range strings.Split(l.text, "\n") {
+				checkSanitized(t, line)
+			}
+		}
+		_ = resultFrom(c.msgs)
+	})
+}
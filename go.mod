@@ -2,15 +2,16 @@ module github.com/common-fate/updatecheck
 
 go 1.19
 
-require github.com/common-fate/clio v1.2.1
+require (
+	github.com/common-fate/clio v1.2.1
+	github.com/mattn/go-isatty v0.0.18
+	golang.org/x/sys v0.7.0
+)
 
 require (
-	github.com/fatih/color v1.13.0 // indirect
 	github.com/mattn/go-colorable v0.1.9 // indirect
-	github.com/mattn/go-isatty v0.0.14 // indirect
 	go.uber.org/atomic v1.7.0 // indirect
 	go.uber.org/multierr v1.6.0 // indirect
 	go.uber.org/zap v1.23.0 // indirect
-	golang.org/x/sys v0.0.0-20210630005230-0f9fa26af87c // indirect
 	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
 )
@@ -0,0 +1,128 @@
+package updatecheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// golden compares got with the contents of testdata/name.golden,
+// rewriting the file instead if the -update flag is set.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s does not match the golden file, which is part of the wire format.\ngot:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+// captured is a request sent by the update checker.
+type captured struct {
+	body      []byte
+	userAgent string
+}
+
+// captureServer returns a fake update service which records the first request it receives in c.
+func captureServer(t *testing.T, c *captured) *httptest.Server {
+	t.Helper()
+	var once sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		once.Do(func() {
+			c.body = body
+			c.userAgent = r.Header.Get("User-Agent")
+		})
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"updateRequired":false,"message":""}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// normalizeUserAgent replaces the parts of a User-Agent header which depend on the build.
+func normalizeUserAgent(ua string) string {
+	ua = strings.Replace(ua, "cf-updatecheck-go/"+getLibraryVersion(), "cf-updatecheck-go/<version>", 1)
+	return strings.Replace(ua, "("+runtime.GOOS+")", "(<os>)", 1)
+}
+
+// normalizeRequest replaces the fields of a request body which depend on the machine
+// with fixed values, after checking that they were detected.
+func normalizeRequest(t *testing.T, body []byte) []byte {
+	t.Helper()
+	var req Request
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Architecture != runtime.GOARCH || req.OS != runtime.GOOS {
+		t.Errorf("arch/os = %s/%s, want %s/%s", req.Architecture, req.OS, runtime.GOARCH, runtime.GOOS)
+	}
+	if req.ClientID == "" || req.Cohort == nil || *req.Cohort != cohort(req.ClientID) {
+		t.Errorf("clientId = %q, cohort = %v, want a client ID and its cohort", req.ClientID, req.Cohort)
+	}
+	cohort := 42
+	req.Architecture = "arm64"
+	req.OS = "darwin"
+	req.OSVersion = "23.4.0"
+	req.InstallMethod = InstallHomebrew
+	req.ClientID = "7d6f6a2e-0d3c-4b8e-9f3a-2c1b0e5d4a79"
+	req.Cohort = &cohort
+	b, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestRequestGolden(t *testing.T) {
+	isolateState(t)
+	var c captured
+	srv := captureServer(t, &c)
+	runCheck(t, GrantedCLI, "v0.20.0", testOptions(srv.URL,
+		WithAnonymousClientID(),
+		WithOSVersion(),
+		WithUpdatePolicy(SameMajor),
+		WithChannel(ChannelBeta),
+	)...)
+	if c.body == nil {
+		t.Fatal("no request was sent")
+	}
+	golden(t, "request", append(normalizeRequest(t, c.body), '\n'))
+	golden(t, "user_agent", []byte(normalizeUserAgent(c.userAgent)+"\n"))
+}
+
+func TestMinimalRequestGolden(t *testing.T) {
+	isolateState(t)
+	var c captured
+	srv := captureServer(t, &c)
+	runCheck(t, GrantedCLI, "v0.20.0", testOptions(srv.URL,
+		WithMinimalTelemetry(),
+		WithAnonymousClientID(),
+		WithOSVersion(),
+	)...)
+	if c.body == nil {
+		t.Fatal("no request was sent")
+	}
+	golden(t, "request_minimal", c.body)
+	golden(t, "user_agent_minimal", []byte(normalizeUserAgent(c.userAgent)+"\n"))
+}
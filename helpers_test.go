@@ -0,0 +1,89 @@
+package updatecheck
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// isolateState stores update checking state in a temporary directory for the rest of the test.
+func isolateState(t testing.TB) {
+	t.Helper()
+	SetStateDir(t.TempDir())
+	t.Cleanup(func() { SetStateDir("") })
+}
+
+// testOptions returns options which check url on every run, without logging.
+func testOptions(url string, opts ...func(*Options)) []func(*Options) {
+	return append([]func(*Options){
+		func(o *Options) { o.URL = url },
+		WithInteractive(true),
+		WithCheckInterval(time.Nanosecond),
+		WithLogger(DiscardLogger),
+	}, opts...)
+}
+
+// runCheck runs a check of app and returns the messages it would print.
+func runCheck(t *testing.T, app App, currentVersion string, opts ...func(*Options)) string {
+	t.Helper()
+	c, err := New(app, currentVersion, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Check()
+	var buf bytes.Buffer
+	c.Fprint(&buf)
+	return buf.String()
+}
+
+// recentlyChecked saves state for app which records a check a minute before now,
+// so that checks are throttled until the check interval has passed.
+func recentlyChecked(t testing.TB, app App, currentVersion string, now time.Time) versionConfig {
+	t.Helper()
+	vc, _ := loadVersionConfig(app, DiscardLogger)
+	vc.Version = currentVersion
+	vc.FirstSeen = now.Add(-time.Hour)
+	vc.LastCheck = now.Add(-time.Minute)
+	vc.Messages = []cachedMessage{{Text: "A new version is available", LatestVersion: "v0.21.0", Update: true}}
+	vc.History = []HistoryEntry{{Time: vc.LastCheck, Version: currentVersion, Decision: DecisionPerformed}}
+	if err := vc.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return vc
+}
+
+// staticBackend is a Backend which always returns the same response.
+type staticBackend Response
+
+func (b staticBackend) Check(context.Context, Request) (Response, error) {
+	return Response(b), nil
+}
+
+// recordingLogger records the messages logged at each level.
+type recordingLogger struct {
+	mu    sync.Mutex
+	debug []string
+	info  []string
+	warn  []string
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.debug = append(l.debug, fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Infof(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.info = append(l.info, fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Warnf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warn = append(l.warn, fmt.Sprintf(format, args...))
+}
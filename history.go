@@ -0,0 +1,66 @@
+package updatecheck
+
+import (
+	"time"
+)
+
+// maxHistory is the number of past checks kept in the version config.
+const maxHistory = 50
+
+// HistoryEntry records the result of a past update check.
+type HistoryEntry struct {
+	// Time is when the check ran.
+	Time time.Time `json:"time"`
+	// Version is the application version which was running.
+	Version string `json:"version"`
+	// LatestVersion is the latest version reported by the server, if it provided one.
+	LatestVersion string `json:"latestVersion,omitempty"`
+	// UpdateRequired is true if the check found an update.
+	UpdateRequired bool `json:"updateRequired,omitempty"`
+	// Decision is whether the check was performed or failed.
+	Decision Decision `json:"decision"`
+	// Verified is true if response signing was enabled and every response was signed and verified.
+	Verified bool `json:"verified,omitempty"`
+}
+
+// addHistory records a check, discarding the oldest entries beyond maxHistory.
+func (vc *versionConfig) addHistory(e HistoryEntry) {
+	vc.History = append(vc.History, e)
+	if len(vc.History) > maxHistory {
+		vc.History = vc.History[len(vc.History)-maxHistory:]
+	}
+}
+
+// History returns up to the n most recent update checks for app, newest first,
+// such as for a `mytool updates history` command showing when updates appeared
+// and when the user upgraded. If n is zero or negative, all of the kept history is returned.
+func History(app App, n int) []HistoryEntry {
+	vc, _ := loadVersionConfig(app, clioLogger{})
+	if n <= 0 || n > len(vc.History) {
+		n = len(vc.History)
+	}
+	entries := make([]HistoryEntry, 0, n)
+	for i := len(vc.History) - 1; i >= len(vc.History)-n; i-- {
+		entries = append(entries, vc.History[i])
+	}
+	return entries
+}
+
+// timeToUpgrade returns how long the user ran an outdated version before upgrading to currentVersion,
+// measured from the first check which found an update for the previous version.
+// It returns false if the user hasn't just upgraded, or if the history doesn't cover the previous version.
+func (vc versionConfig) timeToUpgrade(currentVersion string, now time.Time) (time.Duration, bool) {
+	if vc.Version == "" || vc.Version == currentVersion {
+		return 0, false
+	}
+	var first time.Time
+	for i := len(vc.History) - 1; i >= 0 && vc.History[i].Version == vc.Version; i-- {
+		if vc.History[i].UpdateRequired {
+			first = vc.History[i].Time
+		}
+	}
+	if first.IsZero() || now.Before(first) {
+		return 0, false
+	}
+	return now.Sub(first), true
+}
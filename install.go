@@ -0,0 +1,119 @@
+package updatecheck
+
+import (
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// InstallMethod is how the application binary was installed.
+type InstallMethod string
+
+const (
+	// InstallUnknown means that the install method couldn't be detected.
+	InstallUnknown InstallMethod = ""
+	// InstallHomebrew means that the binary is in a Homebrew cellar.
+	InstallHomebrew InstallMethod = "homebrew"
+	// InstallScoop means that the binary was installed with Scoop on Windows.
+	InstallScoop InstallMethod = "scoop"
+	// InstallApt means that the binary is in a system directory managed by dpkg.
+	InstallApt InstallMethod = "apt"
+	// InstallGo means that the binary was installed with `go install`.
+	InstallGo InstallMethod = "go"
+	// InstallBinary means that the binary was installed by hand, such as from a tarball.
+	InstallBinary InstallMethod = "binary"
+)
+
+// upgradeCommandPlaceholder is replaced with the upgrade command in server messages.
+const upgradeCommandPlaceholder = "{{upgradeCommand}}"
+
+// installMethod caches the detected install method for the lifetime of the process.
+var installMethod struct {
+	once   sync.Once
+	method InstallMethod
+}
+
+// detectInstallMethod returns how the running binary was installed, based on its path.
+func detectInstallMethod() InstallMethod {
+	installMethod.once.Do(func() {
+		exe, err := os.Executable()
+		if err != nil {
+			return
+		}
+		if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+			exe = resolved
+		}
+		installMethod.method = installMethodFromPath(exe)
+	})
+	return installMethod.method
+}
+
+// installMethodFromPath detects the install method from the path of the binary.
+func installMethodFromPath(exe string) InstallMethod {
+	slashed := strings.ToLower(filepath.ToSlash(exe))
+	dir := filepath.Dir(exe)
+	switch {
+	case strings.Contains(slashed, "/cellar/") || strings.Contains(slashed, "/homebrew/") || strings.Contains(slashed, "/linuxbrew/"):
+		return InstallHomebrew
+	case strings.Contains(slashed, "/scoop/"):
+		return InstallScoop
+	case dir == goBinDir():
+		return InstallGo
+	case dir == "/usr/bin" || dir == "/bin" || dir == "/usr/sbin":
+		if _, err := os.Stat("/var/lib/dpkg/status"); err == nil {
+			return InstallApt
+		}
+	}
+	return InstallBinary
+}
+
+// goBinDir returns the directory that `go install` writes binaries to.
+func goBinDir() string {
+	if gobin := os.Getenv("GOBIN"); gobin != "" {
+		return filepath.Clean(gobin)
+	}
+	gopath := os.Getenv("GOPATH")
+	if gopath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		gopath = filepath.Join(home, "go")
+	}
+	// GOPATH may be a list, binaries are installed to the first entry.
+	return filepath.Join(filepath.SplitList(gopath)[0], "bin")
+}
+
+// upgradeCommand returns the command which upgrades the application for the
+// detected install method, such as "brew upgrade granted", or an empty string if it isn't known.
+// Commands configured with WithUpgradeCommands take precedence. For binaries installed
+// with `go install`, the command is derived from the main package path.
+func (o Options) upgradeCommand() string {
+	method := detectInstallMethod()
+	if cmd, ok := o.UpgradeCommands[method]; ok {
+		return cmd
+	}
+	if method == InstallGo {
+		if bi, ok := debug.ReadBuildInfo(); ok && bi.Path != "" {
+			return "go install " + bi.Path + "@latest"
+		}
+	}
+	return ""
+}
+
+// withUpgradeCommand fills in the upgrade command placeholder in a server message.
+// If the message doesn't contain the placeholder, the upgrade hint is returned separately.
+func withUpgradeCommand(msg, cmd string) (text, hint string) {
+	if strings.Contains(msg, upgradeCommandPlaceholder) {
+		if cmd == "" {
+			cmd = "your package manager"
+		}
+		return strings.ReplaceAll(msg, upgradeCommandPlaceholder, cmd), ""
+	}
+	if cmd == "" {
+		return msg, ""
+	}
+	return msg, "To upgrade, run: " + cmd
+}
@@ -0,0 +1,48 @@
+package updatecheck
+
+import (
+	"os"
+
+	"github.com/mattn/go-isatty"
+)
+
+// ciEnvVars are set by common CI providers.
+var ciEnvVars = []string{
+	"CI",
+	"CONTINUOUS_INTEGRATION",
+	"GITHUB_ACTIONS",
+	"GITLAB_CI",
+	"BUILDKITE",
+	"CIRCLECI",
+	"TF_BUILD",
+	"JENKINS_URL",
+	"TEAMCITY_VERSION",
+}
+
+// detectNonInteractive returns a description of why the process appears not to be
+// used interactively, such as running in CI or with stderr redirected,
+// or an empty string if it is interactive.
+func detectNonInteractive() string {
+	for _, name := range ciEnvVars {
+		if v := os.Getenv(name); v != "" && !isFalsy(v) {
+			return "running in CI (" + name + " is set)"
+		}
+	}
+	fd := os.Stderr.Fd()
+	if !isatty.IsTerminal(fd) && !isatty.IsCygwinTerminal(fd) {
+		return "stderr is not a terminal"
+	}
+	return ""
+}
+
+// nonInteractiveReason returns why checks should be skipped because nobody
+// is there to see the message, or an empty string if the check should go ahead.
+func (o Options) nonInteractiveReason() string {
+	switch o.Interactive {
+	case ToggleOn:
+		return ""
+	case ToggleOff:
+		return "the host application is not interactive"
+	}
+	return detectNonInteractive()
+}
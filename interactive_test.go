@@ -0,0 +1,23 @@
+package updatecheck
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectNonInteractiveCI(t *testing.T) {
+	for _, name := range ciEnvVars {
+		t.Setenv(name, "")
+	}
+	// these are commonly set outside CI, such as by shells and build tools.
+	t.Setenv("BUILD_NUMBER", "42")
+	t.Setenv("RUN_ID", "7")
+	if reason := detectNonInteractive(); strings.Contains(reason, "CI") {
+		t.Errorf("detected CI from generic env vars: %s", reason)
+	}
+
+	t.Setenv("GITHUB_ACTIONS", "true")
+	if reason := detectNonInteractive(); !strings.Contains(reason, "GITHUB_ACTIONS") {
+		t.Errorf("didn't detect CI from GITHUB_ACTIONS, reason: %q", reason)
+	}
+}
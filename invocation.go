@@ -0,0 +1,60 @@
+package updatecheck
+
+import (
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+)
+
+// completionArgs are arguments used by CLI frameworks to request shell completions.
+var completionArgs = []string{
+	"__complete",                  // cobra
+	"__completeNoDesc",            // cobra
+	"--generate-bash-completion",  // urfave/cli v1 and v2
+	"--generate-shell-completion", // urfave/cli v3
+}
+
+// completionEnvVars are set by shells and completion frameworks while generating completions.
+var completionEnvVars = []string{"COMP_LINE", "COMP_POINT", "_ARGCOMPLETE"}
+
+// isCompletion returns true if the process was invoked to generate shell completions.
+func isCompletion(args []string) bool {
+	for _, v := range completionEnvVars {
+		if os.Getenv(v) != "" {
+			return true
+		}
+	}
+	for _, a := range args {
+		for _, c := range completionArgs {
+			if a == c {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isCredentialProcess returns true if the process appears to have been invoked as an
+// AWS credential_process, whose stdout is read by the AWS SDK rather than a terminal.
+func isCredentialProcess(args []string) bool {
+	if isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd()) {
+		return false
+	}
+	for _, a := range args {
+		if strings.EqualFold(a, "credential-process") || strings.EqualFold(a, "credential_process") {
+			return true
+		}
+	}
+	return false
+}
+
+// isMachineInvocation returns true if the process was invoked by a machine rather than a user,
+// where stray output would break the protocol being spoken, such as shell completion
+// or an AWS credential_process. The host application can also report this with WithMachineInvocation.
+func isMachineInvocation(o Options) bool {
+	if o.MachineInvocation != nil && o.MachineInvocation() {
+		return true
+	}
+	return isCompletion(os.Args[1:]) || isCredentialProcess(os.Args[1:])
+}
@@ -0,0 +1,54 @@
+package updatecheck
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// AppResult is the result of a check for an application, written by PrintJSON().
+type AppResult struct {
+	// App is the application which was checked.
+	App App `json:"app"`
+	Result
+}
+
+// PrintJSON waits for the checks started by Check() to finish and writes their results to w
+// as a JSON array of AppResult, ordered by application name. This allows CLIs with an
+// `--output json` flag to include update information without writing human-readable text.
+// Checks which were skipped, such as by an environment variable, are omitted.
+func PrintJSON(w io.Writer) error {
+	results := []AppResult{}
+	for _, c := range pendingCheckers() {
+		if res, ok := c.result(); ok {
+			results = append(results, res)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].App < results[j].App
+	})
+	return json.NewEncoder(w).Encode(results)
+}
+
+// PrintJSON waits for the most recent check to finish and writes its result to w as an AppResult
+// in JSON. If the check was skipped, such as by an environment variable, "null" is written.
+func (c *Checker) PrintJSON(w io.Writer) error {
+	var res *AppResult
+	if r, ok := c.result(); ok {
+		res = &r
+	}
+	return json.NewEncoder(w).Encode(res)
+}
+
+// result waits for the most recent check to finish and returns its result,
+// or false if no check was started.
+func (c *Checker) result() (AppResult, bool) {
+	c.mu.Lock()
+	h := c.current
+	c.mu.Unlock()
+	if h == nil {
+		return AppResult{}, false
+	}
+	<-h.done
+	return AppResult{App: c.app, Result: resultFrom(h.msgs)}, true
+}
@@ -4,20 +4,58 @@ import (
 	"encoding/json"
 	"errors"
 	"os"
-	"path"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
-
-	"github.com/common-fate/clio"
 )
 
 type versionConfig struct {
-	dir                 string
-	app                 App
+	dir string
+	app App
+	// LastCheckForUpdates is the weekday of the last check. It is no longer used for throttling,
+	// but is still written so that older versions of this package don't check again immediately.
 	LastCheckForUpdates time.Weekday `json:"lastCheckForUpdates"`
+	// Version is the application version which was running during the last check.
+	Version string `json:"version,omitempty"`
+	// UpdateRequired is true if the last check found an update.
+	UpdateRequired bool `json:"updateRequired,omitempty"`
+	// IgnoredVersions are versions which the user has chosen not to be notified about.
+	IgnoredVersions []string `json:"ignoredVersions,omitempty"`
+	// FirstSeen is when the application was first run.
+	FirstSeen time.Time `json:"firstSeen,omitempty"`
+	// NextCheck is the earliest time that the next check may run, including any jitter.
+	// It is recorded for diagnostics, as throttling is calculated from LastCheck.
+	NextCheck time.Time `json:"nextCheck,omitempty"`
+	// LastCheck is when the last successful check ran.
+	LastCheck time.Time `json:"lastCheck,omitempty"`
+	// ClockOffset is the difference between the update server's clock and the system clock,
+	// measured during the last check if it was larger than the skew tolerance.
+	ClockOffset time.Duration `json:"clockOffset,omitempty"`
+	// Disabled is true if the user has opted out of update checks with Disable().
+	Disabled bool `json:"disabled,omitempty"`
+	// SnoozedUntil is when update messages snoozed with Snooze() are shown again.
+	SnoozedUntil time.Time `json:"snoozedUntil,omitempty"`
+	// ClientID is a random anonymous identifier, only generated if enabled with WithAnonymousClientID().
+	ClientID string `json:"clientId,omitempty"`
+	// Channel is the release channel the user opted into.
+	Channel Channel `json:"channel,omitempty"`
+	// StoppedVersion is the version which the update service told to stop checking for updates.
+	StoppedVersion string `json:"stoppedVersion,omitempty"`
+	// Endpoints maps update checking endpoints to the endpoints they have moved to,
+	// as announced by the update service with a permanent redirect or a newEndpoint field.
+	Endpoints map[string]string `json:"endpoints,omitempty"`
+	// Responses are the last responses from each endpoint which sent an ETag or Last-Modified header.
+	Responses map[string]conditionalResponse `json:"responses,omitempty"`
+	// Messages are the messages from the last successful check, which are shown again
+	// when later checks are skipped.
+	Messages []cachedMessage `json:"messages,omitempty"`
+	// History is the most recent checks which were performed or failed, oldest first.
+	History []HistoryEntry `json:"history,omitempty"`
 }
 
 func (vc versionConfig) Path() string {
-	return path.Join(vc.dir, string(vc.app)+"-update")
+	return filepath.Join(vc.dir, string(vc.app)+"-update")
 }
 
 func (vc versionConfig) Save() error {
@@ -37,39 +75,123 @@ func (vc versionConfig) Save() error {
 	if err != nil {
 		return err
 	}
-	vcfile := path.Join(vc.dir, string(vc.app)+"-update")
-	os.WriteFile(vcfile, data, 0700)
-	return nil
+	return os.WriteFile(vc.Path(), data, 0700)
 }
 
-func loadVersionConfig(app App) (vc versionConfig, ok bool) {
-	vc.app = app
-	cd, err := os.UserConfigDir()
-	if err != nil {
-		clio.Debug("error loading user config dir: %s", err.Error())
-		return
+// isIgnored returns true if the user has chosen not to be notified about version v.
+func (vc versionConfig) isIgnored(v string) bool {
+	for _, ignored := range vc.IgnoredVersions {
+		if strings.TrimPrefix(ignored, "v") == strings.TrimPrefix(v, "v") {
+			return true
+		}
+	}
+	return false
+}
+
+// IgnoreVersion permanently stops the user being notified about a specific version of app,
+// such as a known-bad release which they are intentionally avoiding.
+// Later versions will still be notified about.
+func IgnoreVersion(app App, version string) error {
+	vc, _ := loadVersionConfig(app, clioLogger{})
+	if vc.isIgnored(version) {
+		return nil
+	}
+	vc.IgnoredVersions = append(vc.IgnoredVersions, version)
+	return vc.Save()
+}
+
+// Disable persistently opts the user out of update checks for app,
+// such as from a `mytool updates disable` command. Use Enable to opt back in.
+func Disable(app App) error {
+	vc, _ := loadVersionConfig(app, clioLogger{})
+	vc.Disabled = true
+	return vc.Save()
+}
+
+// Enable reverses Disable, so that update checks for app run again.
+func Enable(app App) error {
+	vc, ok := loadVersionConfig(app, clioLogger{})
+	if !ok || !vc.Disabled {
+		return nil
+	}
+	vc.Disabled = false
+	return vc.Save()
+}
+
+// configDir caches the resolved commonfate config directory for the lifetime of the process.
+var configDir struct {
+	once sync.Once
+	path string
+	err  error
+
+	mu       sync.Mutex
+	override string
+}
+
+// SetStateDir overrides the directory which update checking state is stored in,
+// which defaults to a "commonfate" directory in the user's config directory.
+// It is intended for tests, so that they don't read or modify the real state.
+// Passing an empty string restores the default.
+func SetStateDir(dir string) {
+	configDir.mu.Lock()
+	defer configDir.mu.Unlock()
+	configDir.override = dir
+}
+
+// resolveConfigDir returns the directory that version config files are stored in.
+// The directory is not created here, it is created when the version config is saved.
+func resolveConfigDir() (string, error) {
+	configDir.mu.Lock()
+	override := configDir.override
+	configDir.mu.Unlock()
+	if override != "" {
+		return override, nil
 	}
-	vc.dir = path.Join(cd, "commonfate")
-	err = os.MkdirAll(vc.dir, os.ModePerm)
+	configDir.once.Do(func() {
+		cd, err := os.UserConfigDir()
+		if err != nil {
+			configDir.err = err
+			return
+		}
+		configDir.path = filepath.Join(cd, "commonfate")
+	})
+	return configDir.path, configDir.err
+}
+
+// StatePath returns the path of the file which update checking state
+// for app is stored in, for use in diagnostics.
+// The file may not exist if a check has never completed.
+func StatePath(app App) (string, error) {
+	dir, err := resolveConfigDir()
 	if err != nil {
-		clio.Debug("error creating commonfate config dir: %s", err.Error())
-		return
+		return "", err
 	}
+	vc := versionConfig{dir: dir, app: app}
+	return vc.Path(), nil
+}
 
-	vcfile := path.Join(vc.dir, string(app)+"-update")
-	if _, err := os.Stat(vcfile); errors.Is(err, os.ErrNotExist) {
-		clio.Debug("version config file does not exist: %s", vcfile)
+func loadVersionConfig(app App, log Logger) (vc versionConfig, ok bool) {
+	vc.app = app
+	dir, err := resolveConfigDir()
+	if err != nil {
+		log.Debugf("error loading user config dir: %s", err.Error())
 		return
 	}
+	vc.dir = dir
 
+	vcfile := vc.Path()
 	data, err := os.ReadFile(vcfile)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Debugf("version config file does not exist: %s", vcfile)
+		return
+	}
 	if err != nil {
-		clio.Debug("error reading version config: %s", err.Error())
+		log.Debugf("error reading version config: %s", err.Error())
 		return
 	}
 	err = json.Unmarshal(data, &vc)
 	if err != nil {
-		clio.Debug("error unmarshalling version config: %s", err.Error())
+		log.Debugf("error unmarshalling version config: %s", err.Error())
 		return
 	}
 	ok = true
@@ -0,0 +1,192 @@
+package updatecheck
+
+import (
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// unixTime returns a UTC time which survives a JSON round trip.
+func unixTime(sec int32, nsec uint32) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(sec), int64(nsec%1e9)).UTC()
+}
+
+func TestVersionConfigRoundTrip(t *testing.T) {
+	isolateState(t)
+	dir, err := resolveConfigDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roundTrip := func(version string, required, disabled bool, ignored []string, firstSeen, lastCheck int32, nsec uint32,
+		offset int64, clientID, channel string, endpoints map[string]string, etag, body, text string, severity string) bool {
+		vc := versionConfig{
+			dir:                 dir,
+			app:                 GrantedCLI,
+			LastCheckForUpdates: unixTime(lastCheck, 0).Weekday(),
+			Version:             version,
+			UpdateRequired:      required,
+			FirstSeen:           unixTime(firstSeen, nsec),
+			LastCheck:           unixTime(lastCheck, nsec),
+			NextCheck:           unixTime(lastCheck, 0),
+			ClockOffset:         time.Duration(offset),
+			Disabled:            disabled,
+			ClientID:            clientID,
+			Channel:             Channel(channel),
+		}
+		// omitempty fields are read back as nil, so only set them when they have entries.
+		if len(ignored) > 0 {
+			vc.IgnoredVersions = ignored
+		}
+		if len(endpoints) > 0 {
+			vc.Endpoints = endpoints
+		}
+		if etag != "" {
+			vc.Responses = map[string]conditionalResponse{"https://example.com": {ETag: etag, Body: body}}
+		}
+		if text != "" {
+			vc.Messages = []cachedMessage{{Text: text, LatestVersion: version, Severity: Severity(severity), Update: required}}
+			vc.History = []HistoryEntry{{Time: unixTime(lastCheck, nsec), Version: version, UpdateRequired: required, Decision: DecisionPerformed}}
+		}
+
+		if err := vc.Save(); err != nil {
+			t.Log(err)
+			return false
+		}
+		got, ok := loadVersionConfig(GrantedCLI, DiscardLogger)
+		if !ok {
+			return false
+		}
+		if !reflect.DeepEqual(got, vc) {
+			t.Logf("saved %+v\nloaded %+v", vc, got)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// loadVersionConfigAllocs is the allocation budget for loading the state of a check.
+const loadVersionConfigAllocs = 20
+
+func TestLoadVersionConfigAllocs(t *testing.T) {
+	isolateState(t)
+	recentlyChecked(t, GrantedCLI, "v0.20.0", time.Now())
+
+	if _, ok := loadVersionConfig(GrantedCLI, DiscardLogger); !ok {
+		t.Fatal("expected the version config to load")
+	}
+	if allocs := testing.AllocsPerRun(100, func() { loadVersionConfig(GrantedCLI, DiscardLogger) }); allocs > loadVersionConfigAllocs {
+		t.Errorf("loadVersionConfig made %v allocations, the budget is %d", allocs, loadVersionConfigAllocs)
+	}
+}
+
+func BenchmarkLoadVersionConfig(b *testing.B) {
+	isolateState(b)
+	recentlyChecked(b, GrantedCLI, "v0.20.0", time.Now())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		loadVersionConfig(GrantedCLI, DiscardLogger)
+	}
+}
+
+// resetConfigDir clears the cached config directory, so that it is resolved again
+// from the environment, and restores it when the test finishes.
+func resetConfigDir(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		configDir.mu.Lock()
+		defer configDir.mu.Unlock()
+		configDir.once = sync.Once{}
+		configDir.path, configDir.err, configDir.override = "", nil, ""
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestStatePath(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		goos     string
+		env      map[string]string
+		override string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name: "XDG_CONFIG_HOME",
+			goos: "linux",
+			env:  map[string]string{"XDG_CONFIG_HOME": filepath.Join(dir, "xdg"), "HOME": filepath.Join(dir, "home")},
+			want: filepath.Join(dir, "xdg", "commonfate", "granted-cli-update"),
+		},
+		{
+			name: "HOME",
+			goos: "linux",
+			env:  map[string]string{"XDG_CONFIG_HOME": "", "HOME": filepath.Join(dir, "home")},
+			want: filepath.Join(dir, "home", ".config", "commonfate", "granted-cli-update"),
+		},
+		{
+			name:    "no config dir",
+			goos:    "linux",
+			env:     map[string]string{"XDG_CONFIG_HOME": "", "HOME": ""},
+			wantErr: true,
+		},
+		{
+			name: "macOS",
+			goos: "darwin",
+			env:  map[string]string{"HOME": filepath.Join(dir, "home")},
+			want: filepath.Join(dir, "home", "Library", "Application Support", "commonfate", "granted-cli-update"),
+		},
+		{
+			name: "APPDATA",
+			goos: "windows",
+			env:  map[string]string{"APPDATA": filepath.Join(dir, "appdata")},
+			want: filepath.Join(dir, "appdata", "commonfate", "granted-cli-update"),
+		},
+		{
+			name:    "no APPDATA",
+			goos:    "windows",
+			env:     map[string]string{"APPDATA": ""},
+			wantErr: true,
+		},
+		{
+			name:     "SetStateDir",
+			goos:     runtime.GOOS,
+			env:      map[string]string{"XDG_CONFIG_HOME": filepath.Join(dir, "xdg"), "APPDATA": filepath.Join(dir, "appdata")},
+			override: filepath.Join(dir, "state"),
+			want:     filepath.Join(dir, "state", "granted-cli-update"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.goos != runtime.GOOS {
+				t.Skipf("the config dir is only resolved this way on %s", tt.goos)
+			}
+			resetConfigDir(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if tt.override != "" {
+				SetStateDir(tt.override)
+			}
+			got, err := StatePath(GrantedCLI)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StatePath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("StatePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
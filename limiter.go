@@ -0,0 +1,44 @@
+package updatecheck
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultMaxConcurrentRequests is the default number of requests to update
+// checking endpoints which may be in flight at once, across all apps.
+const defaultMaxConcurrentRequests = 4
+
+// requestSlots limits the number of concurrent requests made by this process,
+// so that a CLI checking many apps or plugins doesn't open a dozen connections at startup.
+var requestSlots = struct {
+	mu sync.Mutex
+	ch chan struct{}
+}{ch: make(chan struct{}, defaultMaxConcurrentRequests)}
+
+// SetMaxConcurrentRequests sets the maximum number of requests to update checking
+// endpoints which may be in flight at once, shared between all apps checked by this process.
+// The default is 4. Values less than 1 are treated as 1.
+func SetMaxConcurrentRequests(n int) {
+	if n < 1 {
+		n = 1
+	}
+	requestSlots.mu.Lock()
+	defer requestSlots.mu.Unlock()
+	requestSlots.ch = make(chan struct{}, n)
+}
+
+// acquireRequestSlot blocks until a request may be made or ctx is cancelled,
+// returning a func which must be called once the request has finished.
+func acquireRequestSlot(ctx context.Context) (release func(), err error) {
+	requestSlots.mu.Lock()
+	ch := requestSlots.ch
+	requestSlots.mu.Unlock()
+
+	select {
+	case ch <- struct{}{}:
+		return func() { <-ch }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
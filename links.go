@@ -0,0 +1,50 @@
+package updatecheck
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// defaultLinkDomains are the domains which links in server messages may point to.
+// Subdomains are also allowed.
+var defaultLinkDomains = []string{"commonfate.io", "granted.dev", "github.com"}
+
+// linkPattern matches URLs in a message, including schemes such as javascript: and
+// file: which terminals or the calling CLI could otherwise present as clickable.
+var linkPattern = regexp.MustCompile(`(?i)\b(?:[a-z][a-z0-9+.-]*://|(?:javascript|vbscript|data|file|mailto):)[^\s<>"]+`)
+
+// removedLink replaces links which aren't allowed.
+const removedLink = "[link removed]"
+
+// filterLinks replaces links in msg which don't use https or which point to a domain
+// outside of the allowlist, so that a tampered message can't present a
+// javascript: or look-alike phishing link.
+func (o Options) filterLinks(msg string) string {
+	return linkPattern.ReplaceAllStringFunc(msg, func(link string) string {
+		// trailing punctuation is usually part of the sentence rather than the link.
+		trimmed := strings.TrimRight(link, ".,;:!?)")
+		if o.linkAllowed(trimmed) {
+			return link
+		}
+		return removedLink + link[len(trimmed):]
+	})
+}
+
+// linkAllowed returns true if link is an https URL on one of the allowed domains or their subdomains.
+func (o Options) linkAllowed(link string) bool {
+	domains := append(append([]string{}, defaultLinkDomains...), o.LinkDomains...)
+	u, err := url.Parse(link)
+	if err != nil || !strings.EqualFold(u.Scheme, "https") || u.User != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	// look-alike hosts such as github.com.example.org or internationalised lookalikes don't match.
+	for _, d := range domains {
+		d = strings.ToLower(d)
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
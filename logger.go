@@ -0,0 +1,62 @@
+package updatecheck
+
+import "github.com/common-fate/clio"
+
+// Logger receives the diagnostic output and update messages from the update check,
+// allowing them to be routed to a logging library such as zap or slog, or silenced.
+type Logger interface {
+	// Debugf logs diagnostic information about the check.
+	Debugf(format string, args ...interface{})
+	// Infof displays an update message to the user.
+	// Warnings and security advisories are displayed with Warnf instead,
+	// if the logger also has a method Warnf(format string, args ...interface{}).
+	Infof(format string, args ...interface{})
+}
+
+// warnLogger is implemented by loggers which can display warnings. If the configured
+// Logger implements it, warnings and security advisories are displayed with Warnf
+// rather than Infof.
+type warnLogger interface {
+	Warnf(format string, args ...interface{})
+}
+
+// warn displays a warning with the logger's Warnf if it has one, or with Infof otherwise.
+func warn(log Logger, format string, args ...interface{}) {
+	if wl, ok := log.(warnLogger); ok {
+		wl.Warnf(format, args...)
+		return
+	}
+	log.Infof(format, args...)
+}
+
+// clioLogger is the default Logger, which uses clio.
+type clioLogger struct{}
+
+func (clioLogger) Debugf(format string, args ...interface{}) { clio.Debugf(format, args...) }
+func (clioLogger) Infof(format string, args ...interface{})  { clio.Infof(format, args...) }
+func (clioLogger) Warnf(format string, args ...interface{})  { clio.Warnf(format, args...) }
+
+// DiscardLogger is a Logger which discards all output.
+var DiscardLogger Logger = discardLogger{}
+
+type discardLogger struct{}
+
+func (discardLogger) Debugf(string, ...interface{}) {}
+func (discardLogger) Infof(string, ...interface{})  {}
+
+// log returns the configured logger, defaulting to clio.
+func (o Options) log() Logger {
+	if o.Logger != nil {
+		return o.Logger
+	}
+	return clioLogger{}
+}
+
+// loggerFor returns the logger configured by opts, for use when the options are invalid.
+func loggerFor(opts ...func(*Options)) Logger {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.log()
+}
@@ -0,0 +1,85 @@
+package updatecheck
+
+import "time"
+
+// defaultMessageTTL is how long a cached message is shown for if the server doesn't set an expiry.
+const defaultMessageTTL = 7 * 24 * time.Hour
+
+// cachedMessage is a message from the last check, stored in the version config
+// so that it can be shown again when later checks are skipped.
+type cachedMessage struct {
+	Text          string        `json:"text"`
+	LatestVersion string        `json:"latestVersion,omitempty"`
+	ReleaseDate   time.Time     `json:"releaseDate,omitempty"`
+	Verified      bool          `json:"verified,omitempty"`
+	Severity      Severity      `json:"severity,omitempty"`
+	Health        ReleaseHealth `json:"health,omitempty"`
+	Actions       []Action      `json:"actions,omitempty"`
+	Update        bool          `json:"update,omitempty"`
+	Required      bool          `json:"required,omitempty"`
+	UpgradeHint   string        `json:"upgradeHint,omitempty"`
+	Changelog     []Release     `json:"changelog,omitempty"`
+	// Critical is read from version configs written before messages had a severity.
+	Critical bool `json:"critical,omitempty"`
+	// Expires is when the message stops being shown.
+	Expires time.Time `json:"expires"`
+}
+
+// cacheMessages stores the messages from a check at 'now' in the version config.
+func (vc *versionConfig) cacheMessages(msgs []message, now time.Time) {
+	vc.Messages = nil
+	for _, m := range msgs {
+		expires := m.expires
+		if expires.IsZero() {
+			expires = now.Add(defaultMessageTTL)
+		}
+		vc.Messages = append(vc.Messages, cachedMessage{
+			Text:          m.text,
+			LatestVersion: m.latestVersion,
+			ReleaseDate:   m.releaseDate,
+			Verified:      m.verified,
+			Severity:      m.severity,
+			Health:        m.health,
+			Actions:       m.actions,
+			Update:        m.update,
+			Required:      m.required,
+			Changelog:     m.changelog,
+			UpgradeHint:   m.upgradeHint,
+			Expires:       expires,
+		})
+	}
+}
+
+// cachedMessages returns the messages from the last check which should still be shown.
+// Messages are dropped once they expire, or when the user upgrades from the version
+// which was running during the last check, or if the user has since ignored the version.
+func (vc versionConfig) cachedMessages(currentVersion string, now time.Time) []message {
+	if vc.Version != currentVersion {
+		return nil
+	}
+	var msgs []message
+	for _, m := range vc.Messages {
+		if !now.Before(m.Expires) || (m.LatestVersion != "" && vc.isIgnored(m.LatestVersion)) {
+			continue
+		}
+		severity := m.Severity
+		if severity == "" && m.Critical {
+			severity = SeverityCritical
+		}
+		msgs = append(msgs, message{
+			text:          m.Text,
+			latestVersion: m.LatestVersion,
+			releaseDate:   m.ReleaseDate,
+			verified:      m.Verified,
+			severity:      severity.normalize(),
+			health:        m.Health,
+			actions:       m.Actions,
+			update:        m.Update,
+			required:      m.Required,
+			changelog:     m.Changelog,
+			upgradeHint:   m.UpgradeHint,
+			expires:       m.Expires,
+		})
+	}
+	return msgs
+}
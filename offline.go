@@ -0,0 +1,40 @@
+package updatecheck
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// isOffline uses a cheap heuristic to detect whether the machine is obviously offline,
+// such as when in airplane mode: no network interface is up with a routable address.
+// If the interfaces can't be listed we assume that we're online.
+func isOffline() bool {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return false
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			// link-local addresses are assigned without a network, so they don't count.
+			if ok && ipnet.IP.IsGlobalUnicast() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isNetworkUnreachable returns true if err was caused by there being no route
+// to the update service. There is no point in retrying the check if so.
+func isNetworkUnreachable(err error) bool {
+	return errors.Is(err, syscall.ENETUNREACH) || errors.Is(err, syscall.EHOSTUNREACH)
+}
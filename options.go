@@ -1,10 +1,950 @@
 package updatecheck
 
-import "net/http"
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
 
 // Options allows aspects of the update checking to be customised.
 type Options struct {
+	// Client is the HTTP client used to call the update checking endpoint.
+	// If nil, a client is constructed from the dial settings below.
+	// The TLS, proxy and dial settings can't be used with a custom Client.
 	Client *http.Client
 	// URL is the update checking endpoint.
+	// It may be a template containing "{{.Tenant}}", such as
+	// "https://update.{{.Tenant}}.example.com/check", which is resolved using Tenant.
 	URL string
+	// Development uses the development update checking endpoint rather than
+	// the production one when URL isn't set, for pre-release builds.
+	Development bool
+	// AdditionalURLs are further update checking endpoints, such as a self-hosted
+	// deployment, which are checked alongside URL. Their messages are merged,
+	// with duplicate messages removed. Like URL, they may contain "{{.Tenant}}".
+	AdditionalURLs []string
+	// Tenant is substituted into URL templates, so that white-labeled distributions
+	// of the CLI use their own tenant's update service. It must be a valid DNS label.
+	Tenant string
+	// Backend is a custom source of update information.
+	// If set, it is used instead of URL and AdditionalURLs.
+	Backend Backend
+	// Timeout is the maximum time to wait for each request to the
+	// update checking endpoint, including reading the response.
+	// Defaults to 3 seconds.
+	Timeout time.Duration
+	// Retries is the number of times a request which failed with a
+	// transient error, such as a server error or timeout, is retried.
+	Retries int
+	// RetryBackoff is the delay before the first retry, which doubles
+	// with each further retry up to 10 seconds. Defaults to 250 milliseconds.
+	RetryBackoff time.Duration
+	// TLSConfig is the TLS configuration used to connect to the update checking endpoint.
+	TLSConfig *tls.Config
+	// CABundlePath is a PEM file of additional CA certificates to trust, such as the CA
+	// of a TLS-intercepting corporate proxy. They are added to the system's roots.
+	CABundlePath string
+	// Proxy chooses the proxy for each request. If nil, HTTPS_PROXY, HTTP_PROXY
+	// and NO_PROXY are used.
+	Proxy func(*http.Request) (*url.URL, error)
+	// DialTimeout is the maximum time to wait for a connection
+	// to the update checking endpoint to be established.
+	DialTimeout time.Duration
+	// DNSTimeout is the maximum time to wait for the update checking
+	// endpoint's hostname to be resolved. It is applied separately to
+	// DialTimeout so that a broken resolver fails the check quickly.
+	DNSTimeout time.Duration
+	// IPPreference controls which IP versions are used to connect
+	// to the update checking endpoint.
+	IPPreference IPPreference
+	// FallbackDelay is how long to wait for a connection using the preferred
+	// IP version before racing a connection using the other IP version.
+	// If zero, a default of 300ms is used. If negative, addresses are dialed
+	// one at a time.
+	FallbackDelay time.Duration
+	// DoHResolverURL is an optional DNS-over-HTTPS resolver endpoint
+	// supporting the JSON API, such as "https://cloudflare-dns.com/dns-query".
+	// If set, it is used instead of the system resolver to look up
+	// the update checking endpoint. If it can't be reached or fails,
+	// other than by answering that the name doesn't exist, the system
+	// resolver is used instead. DNSTimeout applies to each separately.
+	DoHResolverURL string
+	// CollectorURL is an optional endpoint which the outcome of each check is
+	// sent to, allowing organizations running their own update service
+	// to measure upgrade adoption. Nothing is sent unless this is set.
+	CollectorURL string
+	// ReportTimeToUpgrade includes how long the user ran an outdated version before upgrading
+	// in collector reports. It is computed locally from the check history.
+	ReportTimeToUpgrade bool
+	// AnonymousClientID sends a random identifier for this installation, and a cohort
+	// derived from it, in the check request. The identifier is stored in the version config.
+	AnonymousClientID bool
+	// IncludeOSVersion sends the operating system version or kernel release
+	// in the check request, so that the server can target messages
+	// at specific OS versions.
+	IncludeOSVersion bool
+	// ASCII renders messages using only ASCII characters,
+	// for legacy terminals and CI log viewers.
+	// By default this is detected from the TERM variable and the locale.
+	ASCII Toggle
+	// HighContrast renders messages without colours.
+	// By default this is detected from the NO_COLOR and TERM variables.
+	HighContrast Toggle
+	// ChangelogLines is the maximum number of lines of the changelog shown by Print()
+	// when an update is available. The changelog isn't shown if it is zero.
+	ChangelogLines int
+	// Clock returns the current time, which is used for throttling and staleness.
+	// It is intended for tests, and defaults to time.Now.
+	Clock func() time.Time
+	// MinimalTelemetry sends only the application and version with update checks, omitting
+	// the OS, architecture, install method and other optional fields from the request,
+	// the calling package and OS from the User-Agent, and stored response validators.
+	// Outcomes aren't reported to the collector. Users can also enable it by setting
+	// <APP>_UPDATE_CHECK_MINIMAL or GRANTED_UPDATE_CHECK_MINIMAL to a truthy value.
+	MinimalTelemetry bool
+	// Trailer replaces the update messages with a single compact line,
+	// which is only shown when an update exists.
+	Trailer bool
+	// TrailerFormat is the line shown in trailer mode. "{{latestVersion}}" and
+	// "{{upgradeCommand}}" are replaced with the latest version and the upgrade command.
+	TrailerFormat string
+	// Interactive controls whether checks run. Checks are skipped when the
+	// process isn't used interactively, as nobody would see the message.
+	// By default this is detected from CI environment variables and whether
+	// stderr is a terminal.
+	Interactive Toggle
+	// SingleLine collapses messages into a single line of plain text
+	// without decorations, for users of screen readers.
+	SingleLine bool
+	// LinkDomains are domains which links in server messages may point to,
+	// in addition to commonfate.io, granted.dev and github.com.
+	// Other links, and links which don't use https, are removed.
+	LinkDomains []string
+	// CompareLocally decides whether an update is required by comparing the
+	// current version with the latest version returned by the server using
+	// semver rules, rather than relying on the server's decision.
+	CompareLocally bool
+	// Channel is the release channel the user receives updates from.
+	// It is remembered in the version config, and if empty the channel
+	// from the previous check is used.
+	Channel Channel
+	// UpdatePolicy controls which new releases the user is notified about.
+	// By default the user is notified about all releases.
+	UpdatePolicy UpdatePolicy
+	// FirstCheckDelay skips checks until this long after the application was first run,
+	// so that users aren't prompted to update immediately after installing.
+	FirstCheckDelay time.Duration
+	// CheckInterval is the minimum time between checks. Defaults to 24 hours.
+	// It has no effect if a custom Throttle is provided.
+	CheckInterval time.Duration
+	// Throttle decides how often checks run. If nil, checks run at most
+	// once per CheckInterval, with Jitter applied.
+	Throttle Throttle
+	// CronSchedule is a cron expression for when checks run, such as "0 9 * * MON".
+	// It can't be used with a custom Throttle.
+	CronSchedule string
+	// Jitter delays each check by a random fraction of CheckInterval, between 0 and 1,
+	// to spread checks across the fleet.
+	Jitter float64
+	// OnTimings is called with the duration of each phase of the check
+	// once the check has finished, to help diagnose slow CLI startup.
+	OnTimings func(Timings)
+	// NetworkAllowed is called before any network requests are made.
+	// If it returns false, the check is skipped.
+	NetworkAllowed func() bool
+	// ConsentProvider is used to check the host application's telemetry consent
+	// before sending optional telemetry such as the OS version and collector reports.
+	ConsentProvider ConsentProvider
+	// PolicyPath is the path to an optional organization-mandated Policy,
+	// which overrides these options. It must be signed with the private key
+	// corresponding to PolicyPublicKey.
+	PolicyPath string
+	// PolicyPublicKey is the Ed25519 public key used to verify the policy.
+	PolicyPublicKey ed25519.PublicKey
+	// ResponsePublicKey is the Ed25519 public key used to verify responses from the update service.
+	// Signed messages are shown with a verified indicator, and unsigned critical messages are not shown.
+	ResponsePublicKey ed25519.PublicKey
+	// OnDecision is called with a structured record of whether the check
+	// was skipped, performed or failed.
+	OnDecision func(CheckDecision)
+	// ClockSkew is the tolerated difference between the system clock and the
+	// update server's clock, and how far in the future stored timestamps may be
+	// before they are discarded. If zero, a default of 5 minutes is used.
+	ClockSkew time.Duration
+	// ProcessThrottle also throttles checks within the process, for environments such as
+	// ephemeral containers where the version config isn't persisted. It uses the monotonic
+	// clock, unless a clock is set with WithClock.
+	ProcessThrottle bool
+	// ImageBuildDateEnv is an environment variable containing the build date of the
+	// container image, in RFC 3339 format or as a Unix timestamp. If set, checks are
+	// skipped while the image is younger than ImageMinAge.
+	ImageBuildDateEnv string
+	// ImageMinAge is how old the container image must be before checks run.
+	ImageMinAge time.Duration
+	// BuildDate is when the running binary was built, such as from SOURCE_DATE_EPOCH.
+	// If zero, the VCS commit time embedded by the Go toolchain is used.
+	BuildDate time.Time
+	// MaxBuildAge is the age after which users are warned that their build is old,
+	// even if the update service can't be reached. If zero, no warning is shown.
+	MaxBuildAge time.Duration
+	// ReleaseSnapshot is JSON release metadata compiled into the binary, in the format of
+	// ReleaseSnapshot. If update checks don't succeed, users are warned when the newest
+	// release in the snapshot is older than ReleaseSnapshotMaxAge.
+	ReleaseSnapshot []byte
+	// ReleaseSnapshotMaxAge is the age of the newest release in the snapshot after which
+	// users are warned. Defaults to 6 months.
+	ReleaseSnapshotMaxAge time.Duration
+	// StalenessPeriod is how long update checks can fail before users are told that
+	// their version may be out of date. If zero, no notice is shown.
+	StalenessPeriod time.Duration
+	// MachineInvocation is called to check whether the process was invoked by a machine
+	// rather than a user, in addition to the built-in detection of shell completion and
+	// credential process invocations. If it returns true, nothing is checked or printed.
+	MachineInvocation func() bool
+	// Verbosity is called when printing to get the host application's current verbosity.
+	// If nil, VerbosityNormal is assumed.
+	Verbosity func() Verbosity
+	// MinimumVerbosity is the lowest host verbosity at which messages are printed.
+	// The default, VerbosityNormal, means that quiet runs never show messages.
+	MinimumVerbosity Verbosity
+	// UpgradeCommands are the commands which upgrade the application for each install method,
+	// such as {InstallHomebrew: "brew upgrade granted"}. The command for the detected
+	// install method is shown with update messages.
+	UpgradeCommands map[InstallMethod]string
+	// UpgradeFunc upgrades the application when the user chooses an ActionRunUpgrade action.
+	UpgradeFunc func(ctx context.Context) error
+	// DisableEnvVar is an additional environment variable which disables
+	// update checks when set to a truthy value, such as "MYTOOL_NO_UPDATE_CHECK".
+	DisableEnvVar string
+	// Logger receives diagnostic output and update messages.
+	// If nil, clio is used.
+	Logger Logger
+
+	// clientID is the anonymous client ID sent with the request, if enabled.
+	clientID string
+	// pinned are the endpoints set by a signed policy, which the update service can't move.
+	pinned map[string]bool
+	// conditional holds the last response from each endpoint, for conditional requests.
+	conditional map[string]conditionalResponse
+	// frequency is read from environment variables by Check().
+	frequency frequency
+	// caller is the package using the library, which is sent in the User-Agent header.
+	// It's found by NewOptions, as the stack no longer leads to the caller once a check starts.
+	caller string
+	// client is constructed by NewOptions when no custom Client is provided.
+	client *http.Client
+}
+
+// Default update checking endpoints.
+const (
+	prodURL = "https://update.commonfate.io/check"
+	devURL  = "https://update-dev.commonfate.io/check"
+)
+
+// OptionError is returned by NewOptions if the options are invalid.
+type OptionError struct {
+	// Option is the name of the invalid Options field.
+	Option string
+	// Reason describes why the option is invalid.
+	Reason string
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("invalid update check option %s: %s", e.Option, e.Reason)
+}
+
+// NewOptions applies opts, validates the resulting combination of options
+// and fills in defaults for any options which weren't provided.
+// 'prod' selects the default update checking endpoint, unless Development is set.
+// If the options are invalid an *OptionError is returned.
+func NewOptions(prod bool, opts ...func(*Options)) (Options, error) {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	err := o.resolveTenant()
+	if err != nil {
+		return Options{}, err
+	}
+	err = o.validate()
+	if err != nil {
+		return Options{}, err
+	}
+
+	if o.URL == "" {
+		o.URL = devURL
+		if prod && !o.Development {
+			o.URL = prodURL
+		}
+	}
+	if o.Timeout == 0 {
+		o.Timeout = 3 * time.Second
+	}
+	if o.RetryBackoff == 0 {
+		o.RetryBackoff = 250 * time.Millisecond
+	}
+	if o.DialTimeout == 0 {
+		o.DialTimeout = 5 * time.Second
+	}
+	if o.DNSTimeout == 0 {
+		o.DNSTimeout = time.Second
+	}
+	if o.ClockSkew == 0 {
+		o.ClockSkew = 5 * time.Minute
+	}
+	if o.CheckInterval == 0 {
+		o.CheckInterval = 24 * time.Hour
+	}
+	if o.ReleaseSnapshotMaxAge == 0 {
+		o.ReleaseSnapshotMaxAge = 6 * 30 * 24 * time.Hour
+	}
+	if o.CronSchedule != "" {
+		// the schedule has already been validated.
+		o.Throttle, _ = Cron(o.CronSchedule)
+	}
+	o.caller = callerPackage()
+	if o.Client == nil {
+		o.client = o.newHTTPClient()
+	}
+	return o, nil
+}
+
+// validate returns an *OptionError if the options are inconsistent.
+func (o Options) validate() error {
+	if o.Client != nil {
+		if o.DialTimeout != 0 || o.DNSTimeout != 0 || o.IPPreference != IPDefault || o.FallbackDelay != 0 || o.DoHResolverURL != "" {
+			return &OptionError{Option: "Client", Reason: "dial settings have no effect when a custom Client is provided"}
+		}
+		if o.TLSConfig != nil || o.CABundlePath != "" || o.Proxy != nil {
+			return &OptionError{Option: "Client", Reason: "TLS and proxy settings have no effect when a custom Client is provided"}
+		}
+	}
+	if o.URL != "" {
+		if err := validateURL(o.URL, false); err != nil {
+			return &OptionError{Option: "URL", Reason: err.Error()}
+		}
+	}
+	for _, u := range o.AdditionalURLs {
+		if err := validateURL(u, false); err != nil {
+			return &OptionError{Option: "AdditionalURLs", Reason: err.Error()}
+		}
+	}
+	if o.PolicyPath != "" && len(o.PolicyPublicKey) != ed25519.PublicKeySize {
+		return &OptionError{Option: "PolicyPublicKey", Reason: "an Ed25519 public key is required to verify the policy"}
+	}
+	if len(o.ResponsePublicKey) > 0 && len(o.ResponsePublicKey) != ed25519.PublicKeySize {
+		return &OptionError{Option: "ResponsePublicKey", Reason: "must be an Ed25519 public key"}
+	}
+	if o.DialTimeout < 0 {
+		return &OptionError{Option: "DialTimeout", Reason: "must not be negative"}
+	}
+	if o.DNSTimeout < 0 {
+		return &OptionError{Option: "DNSTimeout", Reason: "must not be negative"}
+	}
+	if o.ChangelogLines < 0 {
+		return &OptionError{Option: "ChangelogLines", Reason: "must not be negative"}
+	}
+	switch o.IPPreference {
+	case IPDefault, PreferIPv4, IPv4Only:
+	default:
+		return &OptionError{Option: "IPPreference", Reason: fmt.Sprintf("unknown IP preference %d", o.IPPreference)}
+	}
+	if o.DoHResolverURL != "" {
+		// a DoH resolver served over plain HTTP could be hijacked just like DNS.
+		if err := validateURL(o.DoHResolverURL, true); err != nil {
+			return &OptionError{Option: "DoHResolverURL", Reason: err.Error()}
+		}
+	}
+	if o.CollectorURL != "" {
+		if err := validateURL(o.CollectorURL, false); err != nil {
+			return &OptionError{Option: "CollectorURL", Reason: err.Error()}
+		}
+	}
+	if o.ClockSkew < 0 {
+		return &OptionError{Option: "ClockSkew", Reason: "must not be negative"}
+	}
+	if o.ImageMinAge < 0 {
+		return &OptionError{Option: "ImageMinAge", Reason: "must not be negative"}
+	}
+	if o.MaxBuildAge < 0 {
+		return &OptionError{Option: "MaxBuildAge", Reason: "must not be negative"}
+	}
+	if o.ReleaseSnapshotMaxAge < 0 {
+		return &OptionError{Option: "ReleaseSnapshotMaxAge", Reason: "must not be negative"}
+	}
+	if len(o.ReleaseSnapshot) > 0 {
+		if _, err := parseReleaseSnapshot(o.ReleaseSnapshot); err != nil {
+			return &OptionError{Option: "ReleaseSnapshot", Reason: err.Error()}
+		}
+	}
+	if o.StalenessPeriod < 0 {
+		return &OptionError{Option: "StalenessPeriod", Reason: "must not be negative"}
+	}
+	if o.FirstCheckDelay < 0 {
+		return &OptionError{Option: "FirstCheckDelay", Reason: "must not be negative"}
+	}
+	if o.Timeout < 0 {
+		return &OptionError{Option: "Timeout", Reason: "must not be negative"}
+	}
+	if o.Retries < 0 {
+		return &OptionError{Option: "Retries", Reason: "must not be negative"}
+	}
+	if o.RetryBackoff < 0 {
+		return &OptionError{Option: "RetryBackoff", Reason: "must not be negative"}
+	}
+	if o.CronSchedule != "" {
+		if o.Throttle != nil {
+			return &OptionError{Option: "CronSchedule", Reason: "can't be used with a custom Throttle"}
+		}
+		if _, err := Cron(o.CronSchedule); err != nil {
+			return &OptionError{Option: "CronSchedule", Reason: err.Error()}
+		}
+	}
+	if o.CheckInterval < 0 {
+		return &OptionError{Option: "CheckInterval", Reason: "must not be negative"}
+	}
+	if o.Jitter < 0 || o.Jitter > 1 {
+		return &OptionError{Option: "Jitter", Reason: "must be between 0 and 1"}
+	}
+	switch o.UpdatePolicy {
+	case AllReleases, SameMajor, SameMinor:
+	default:
+		return &OptionError{Option: "UpdatePolicy", Reason: fmt.Sprintf("unknown update policy %q", o.UpdatePolicy)}
+	}
+	return nil
+}
+
+// validateURL returns an error if u isn't an absolute HTTP or HTTPS URL.
+// If requireHTTPS is true, only HTTPS URLs are allowed.
+func validateURL(u string, requireHTTPS bool) error {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return err
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("%q is not an absolute URL", u)
+	}
+	if parsed.Scheme == "https" || (parsed.Scheme == "http" && !requireHTTPS) {
+		return nil
+	}
+	if requireHTTPS {
+		return fmt.Errorf("%q must use https", u)
+	}
+	return fmt.Errorf("%q must use http or https", u)
+}
+
+// IPPreference controls which IP versions are used to connect
+// to the update checking endpoint.
+type IPPreference int
+
+const (
+	// IPDefault races IPv6 and IPv4 connections, preferring the
+	// IP version of the first address returned by the resolver.
+	IPDefault IPPreference = iota
+	// PreferIPv4 races IPv6 and IPv4 connections, preferring IPv4.
+	// This is useful on networks with broken IPv6 connectivity.
+	PreferIPv4
+	// IPv4Only never connects over IPv6.
+	IPv4Only
+)
+
+// WithTimeout sets the maximum time to wait for each request to the update checking
+// endpoint, so that a hanging server can't block Print(). It defaults to 3 seconds.
+func WithTimeout(d time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.Timeout = d
+	}
+}
+
+// WithRetries retries requests which fail with a transient error, such as a server error
+// or timeout, up to n times. The first retry is after backoff, doubling with each further
+// retry. If backoff is zero, a default of 250 milliseconds is used.
+func WithRetries(n int, backoff time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.Retries = n
+		o.RetryBackoff = backoff
+	}
+}
+
+// WithTLSConfig connects to the update checking endpoint using cfg.
+func WithTLSConfig(cfg *tls.Config) func(*Options) {
+	return func(o *Options) {
+		o.TLSConfig = cfg
+	}
+}
+
+// WithCABundle trusts the CA certificates in the PEM file at path in addition to the system's roots,
+// such as the CA of a TLS-intercepting corporate proxy.
+func WithCABundle(path string) func(*Options) {
+	return func(o *Options) {
+		o.CABundlePath = path
+	}
+}
+
+// WithProxy chooses the proxy for each request using proxy, rather than
+// the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables.
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) func(*Options) {
+	return func(o *Options) {
+		o.Proxy = proxy
+	}
+}
+
+// WithDialTimeout sets the maximum time to wait for a connection
+// to the update checking endpoint to be established.
+func WithDialTimeout(d time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.DialTimeout = d
+	}
+}
+
+// WithDNSTimeout sets the maximum time to wait for the update checking
+// endpoint's hostname to be resolved.
+func WithDNSTimeout(d time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.DNSTimeout = d
+	}
+}
+
+// WithIPPreference sets which IP versions are used to connect
+// to the update checking endpoint.
+func WithIPPreference(p IPPreference) func(*Options) {
+	return func(o *Options) {
+		o.IPPreference = p
+	}
+}
+
+// WithFallbackDelay sets how long to wait for a connection using the preferred
+// IP version before racing a connection using the other IP version.
+func WithFallbackDelay(d time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.FallbackDelay = d
+	}
+}
+
+// WithDoHResolver resolves the update checking endpoint using a DNS-over-HTTPS
+// resolver supporting the JSON API, such as "https://cloudflare-dns.com/dns-query".
+// This ensures the genuine update service is reached on networks which hijack DNS.
+// If the resolver can't be reached, the system resolver is used instead.
+func WithDoHResolver(url string) func(*Options) {
+	return func(o *Options) {
+		o.DoHResolverURL = url
+	}
+}
+
+// WithCollector sends the outcome of each check (up to date, update available,
+// updated or ignored) to the provided URL. This is intended for organizations
+// running their own update service who want to measure upgrade adoption.
+func WithCollector(url string) func(*Options) {
+	return func(o *Options) {
+		o.CollectorURL = url
+	}
+}
+
+// WithTimeToUpgrade reports how long the user ran an outdated version before upgrading
+// to the collector configured with WithCollector(), so that real-world patch latency can
+// be measured. It is computed locally from the check history, so no per-user tracking
+// is required on the server. Like other optional telemetry, it is only sent with consent.
+func WithTimeToUpgrade() func(*Options) {
+	return func(o *Options) {
+		o.ReportTimeToUpgrade = true
+	}
+}
+
+// WithAnonymousClientID sends a random identifier for this installation in the check request,
+// along with a stable cohort between 0 and 99 derived from it, so that the server can experiment
+// with message phrasing and measure which messages lead to upgrades. The identifier isn't derived
+// from anything about the user or machine. Like other optional telemetry, it is only sent with consent.
+func WithAnonymousClientID() func(*Options) {
+	return func(o *Options) {
+		o.AnonymousClientID = true
+	}
+}
+
+// WithOSVersion opts in to sending the operating system version or kernel release
+// in the check request, so that the server can target messages such as
+// deprecation notices at specific OS versions.
+func WithOSVersion() func(*Options) {
+	return func(o *Options) {
+		o.IncludeOSVersion = true
+	}
+}
+
+// WithChangelog shows the release notes for the versions since the running version
+// when an update is available, if the server provides them, truncated to maxLines lines.
+func WithChangelog(maxLines int) func(*Options) {
+	return func(o *Options) {
+		o.ChangelogLines = maxLines
+	}
+}
+
+// WithClock uses clock rather than time.Now as the current time for throttling, snoozing and staleness,
+// so that tests can control when checks run.
+func WithClock(clock func() time.Time) func(*Options) {
+	return func(o *Options) {
+		o.Clock = clock
+	}
+}
+
+// now returns the current time from the configured clock.
+func (o Options) now() time.Time {
+	if o.Clock != nil {
+		return o.Clock()
+	}
+	return time.Now()
+}
+
+// WithMinimalTelemetry sends only the application and version with update checks,
+// for organizations whose security reviews treat the OS, architecture and calling
+// package as fingerprinting. Updates are still filtered by the channel and update
+// policy locally, but the server can't tailor its response to the platform.
+func WithMinimalTelemetry() func(*Options) {
+	return func(o *Options) {
+		o.MinimalTelemetry = true
+	}
+}
+
+// WithTrailer shows a single compact line, only when an update exists, rather than the
+// update messages, such as "↑ v0.21.0 available – run `granted upgrade`". If format is empty,
+// that format is used. "{{latestVersion}}" and "{{upgradeCommand}}" in format are replaced
+// with the latest version and the command configured with WithUpgradeCommands.
+func WithTrailer(format string) func(*Options) {
+	return func(o *Options) {
+		o.Trailer = true
+		o.TrailerFormat = format
+	}
+}
+
+// WithInteractive overrides whether the process is used interactively. By default, checks
+// are skipped in CI and when stderr isn't a terminal. Use WithInteractive(true) for host
+// applications which display messages with Fprint() or CheckNow().
+func WithInteractive(enabled bool) func(*Options) {
+	return func(o *Options) {
+		o.Interactive = toggle(enabled)
+	}
+}
+
+// WithASCII overrides whether messages are rendered using only ASCII characters.
+func WithASCII(enabled bool) func(*Options) {
+	return func(o *Options) {
+		o.ASCII = toggle(enabled)
+	}
+}
+
+// WithHighContrast overrides whether messages are rendered without colours.
+func WithHighContrast(enabled bool) func(*Options) {
+	return func(o *Options) {
+		o.HighContrast = toggle(enabled)
+	}
+}
+
+// WithSingleLine collapses messages into a single line of plain text
+// without decorations, so that screen readers don't read out
+// box drawing characters and other symbols.
+func WithSingleLine() func(*Options) {
+	return func(o *Options) {
+		o.SingleLine = true
+	}
+}
+
+// WithLinkDomains allows links in server messages to point to domains, and their subdomains,
+// such as the domain of an internal documentation site.
+func WithLinkDomains(domains ...string) func(*Options) {
+	return func(o *Options) {
+		o.LinkDomains = append(o.LinkDomains, domains...)
+	}
+}
+
+// WithLocalComparison compares the current version with the latest version
+// returned by the server locally, using semver rules. This allows the package
+// to be used against simple static endpoints, such as a JSON file containing
+// {"latestVersion": "v1.2.3"}.
+func WithLocalComparison() func(*Options) {
+	return func(o *Options) {
+		o.CompareLocally = true
+	}
+}
+
+// WithChannel checks for updates on a release channel, so that users on nightly
+// builds are offered nightly updates and stable users are never offered pre-releases.
+// The channel is remembered for later checks which don't specify one.
+func WithChannel(c Channel) func(*Options) {
+	return func(o *Options) {
+		o.Channel = c
+	}
+}
+
+// WithUpdatePolicy controls which new releases the user is notified about,
+// such as only minor and patch releases of the current major version (SameMajor).
+func WithUpdatePolicy(p UpdatePolicy) func(*Options) {
+	return func(o *Options) {
+		o.UpdatePolicy = p
+	}
+}
+
+// WithFirstCheckDelay skips checks until d after the application was first run,
+// so that users aren't prompted to update on the very first command after installing.
+func WithFirstCheckDelay(d time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.FirstCheckDelay = d
+	}
+}
+
+// WithThrottle decides how often checks run using t, such as Daily() or Interval(time.Hour),
+// or a custom Throttle for products with unusual release cadences.
+// The <APP>_UPDATE_CHECK environment variables take precedence over the throttle.
+func WithThrottle(t Throttle) func(*Options) {
+	return func(o *Options) {
+		o.Throttle = t
+	}
+}
+
+// WithCronSchedule runs checks according to a cron expression, such as "0 9 * * MON"
+// to align update awareness with a team's weekly maintenance rhythm. The check runs on
+// the first invocation after each scheduled time. See Cron() for the supported syntax.
+func WithCronSchedule(expr string) func(*Options) {
+	return func(o *Options) {
+		o.CronSchedule = expr
+	}
+}
+
+// WithCheckInterval sets the minimum time between checks, which defaults to 24 hours.
+func WithCheckInterval(d time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.CheckInterval = d
+	}
+}
+
+// WithJitter delays each check by a random fraction of the check interval, up to frac (between 0 and 1),
+// to spread checks across the fleet. The chosen time is stored in the version config.
+func WithJitter(frac float64) func(*Options) {
+	return func(o *Options) {
+		o.Jitter = frac
+	}
+}
+
+// WithTimingHook calls f with the duration of each phase of the check
+// (state load, throttle decision, DNS, connect, TLS and total) once the check has finished.
+func WithTimingHook(f func(Timings)) func(*Options) {
+	return func(o *Options) {
+		o.OnTimings = f
+	}
+}
+
+// WithNetworkAllowed allows host applications with an offline mode to veto
+// the update check at runtime. f is called before any network requests
+// are made, and the check is skipped if it returns false.
+func WithNetworkAllowed(f func() bool) func(*Options) {
+	return func(o *Options) {
+		o.NetworkAllowed = f
+	}
+}
+
+// WithConsentProvider reuses the host application's telemetry consent state.
+// Optional telemetry, such as the OS version and collector reports,
+// is only sent if p reports that the user has consented.
+func WithConsentProvider(p ConsentProvider) func(*Options) {
+	return func(o *Options) {
+		o.ConsentProvider = p
+	}
+}
+
+// WithBackend checks for updates using b rather than the Common Fate update service,
+// such as a backend which reads release manifests from an internal artifact server.
+func WithBackend(b Backend) func(*Options) {
+	return func(o *Options) {
+		o.Backend = b
+	}
+}
+
+// WithDevelopment checks for updates using the development update checking endpoint
+// rather than the production one, for pre-release builds. It has no effect if a URL is set.
+func WithDevelopment() func(*Options) {
+	return func(o *Options) {
+		o.Development = true
+	}
+}
+
+// WithAdditionalEndpoint checks url alongside the default update checking endpoint.
+// This is useful for users of both the SaaS and a self-hosted deployment,
+// so that deployment-specific advisories and CLI updates are both shown.
+// Messages with the same ID are only shown once.
+func WithAdditionalEndpoint(url string) func(*Options) {
+	return func(o *Options) {
+		o.AdditionalURLs = append(o.AdditionalURLs, url)
+	}
+}
+
+// WithTenant sets the tenant which is substituted into URL templates containing "{{.Tenant}}",
+// such as "https://update.{{.Tenant}}.example.com/check".
+func WithTenant(tenant string) func(*Options) {
+	return func(o *Options) {
+		o.Tenant = tenant
+	}
+}
+
+// WithSignedPolicy applies an organization-mandated Policy from path, such as one
+// distributed by MDM, overriding the application's own options. The policy must be
+// accompanied by a base64-encoded Ed25519 signature at path + ".sig", made with the
+// private key for publicKey. If the policy doesn't exist or can't be verified,
+// the application's own options are used.
+func WithSignedPolicy(path string, publicKey ed25519.PublicKey) func(*Options) {
+	return func(o *Options) {
+		o.PolicyPath = path
+		o.PolicyPublicKey = publicKey
+	}
+}
+
+// WithSignedResponses verifies responses from the update service against publicKey.
+// Each request includes a random X-Updatecheck-Nonce header. The service signs
+// "updatecheck-response-v1\n<application>\n<version>\n<nonce>\n<body>" with Ed25519,
+// using the application and version from the request, and sends the base64-encoded
+// signature in the X-Signature header, including with a 304 Not Modified response for
+// the stored body. Messages from signed responses are shown with a verified indicator,
+// and critical messages from unsigned responses are not shown.
+func WithSignedResponses(publicKey ed25519.PublicKey) func(*Options) {
+	return func(o *Options) {
+		o.ResponsePublicKey = publicKey
+	}
+}
+
+// WithDecisionHook calls f with a structured record of whether each check
+// was skipped (and why), performed or failed, so that host applications can
+// include it in their own debug output without parsing debug logs.
+func WithDecisionHook(f func(CheckDecision)) func(*Options) {
+	return func(o *Options) {
+		o.OnDecision = f
+	}
+}
+
+// WithClockSkewTolerance sets the tolerated difference between the system clock and
+// the update server's clock. Stored timestamps further in the future than this are
+// discarded, which happens when the system clock moves backwards.
+func WithClockSkewTolerance(d time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.ClockSkew = d
+	}
+}
+
+// WithProcessThrottle also applies the throttle within the process, such as checking at most once
+// per check interval, in addition to the throttling state stored on disk. This is useful in ephemeral containers, where the
+// version config doesn't persist between runs.
+func WithProcessThrottle() func(*Options) {
+	return func(o *Options) {
+		o.ProcessThrottle = true
+	}
+}
+
+// WithContainerImageAge skips checks while the container image is younger than minAge.
+// The image build date is read from the environment variable envVar, which should
+// be set when building the image, in RFC 3339 format or as a Unix timestamp.
+func WithContainerImageAge(envVar string, minAge time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.ImageBuildDateEnv = envVar
+		o.ImageMinAge = minAge
+	}
+}
+
+// WithBuildDate sets when the running binary was built, such as from SOURCE_DATE_EPOCH
+// passed in with -ldflags. If not set, the VCS commit time embedded by the Go toolchain is used.
+func WithBuildDate(t time.Time) func(*Options) {
+	return func(o *Options) {
+		o.BuildDate = t
+	}
+}
+
+// WithMaxBuildAge warns users when the running binary is older than d,
+// such as "This build of granted-cli is 9 months old", using only local information.
+// The warning is shown even if the update service can't be reached,
+// unless the last check found that there is no newer version.
+func WithMaxBuildAge(d time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.MaxBuildAge = d
+	}
+}
+
+// WithReleaseSnapshot compiles release metadata into the binary, so that users are warned
+// about extreme staleness even when the update service can't be reached. data is JSON such as
+// {"latestVersion": "v0.21.0", "releaseDate": "2023-06-01T00:00:00Z"}, typically generated
+// at build time and included with go:embed. The warning is shown if the latest release in the
+// snapshot is older than maxAge, or 6 months if maxAge is zero.
+func WithReleaseSnapshot(data []byte, maxAge time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.ReleaseSnapshot = data
+		o.ReleaseSnapshotMaxAge = maxAge
+	}
+}
+
+// WithStalenessNotice tells users that their version may be out of date if no update
+// check has succeeded for d, such as for air-gapped machines which can never reach
+// the update service.
+func WithStalenessNotice(d time.Duration) func(*Options) {
+	return func(o *Options) {
+		o.StalenessPeriod = d
+	}
+}
+
+// WithMachineInvocation reports whether the process was invoked by a machine rather than a user,
+// where stray output would break the protocol being spoken. This supplements the built-in
+// detection of shell completion and AWS credential_process invocations.
+// If f returns true, updates are neither checked nor printed.
+func WithMachineInvocation(f func() bool) func(*Options) {
+	return func(o *Options) {
+		o.MachineInvocation = f
+	}
+}
+
+// WithVerbosity lets updatecheck query the host application's verbosity, such as from its
+// --quiet and --verbose flags, when printing. Quiet runs don't show messages, and
+// verbose runs show extra detail such as the latest version and its release date.
+func WithVerbosity(f func() Verbosity) func(*Options) {
+	return func(o *Options) {
+		o.Verbosity = f
+	}
+}
+
+// WithMinimumVerbosity sets the lowest host verbosity at which messages are printed.
+// For example, VerbosityVerbose only shows messages when the host is run with --verbose.
+func WithMinimumVerbosity(level Verbosity) func(*Options) {
+	return func(o *Options) {
+		o.MinimumVerbosity = level
+	}
+}
+
+// WithUpgradeCommands sets the commands which upgrade the application for each install method,
+// such as {InstallHomebrew: "brew upgrade granted", InstallScoop: "scoop update granted"}.
+// The command for the detected install method is shown after update messages, or substituted
+// for {{upgradeCommand}} in the message. For `go install`, a command is derived automatically.
+func WithUpgradeCommands(commands map[InstallMethod]string) func(*Options) {
+	return func(o *Options) {
+		o.UpgradeCommands = commands
+	}
+}
+
+// WithUpgradeFunc sets the function which upgrades the application when the user
+// chooses a "run-upgrade" action, such as running the package manager.
+func WithUpgradeFunc(upgrade func(ctx context.Context) error) func(*Options) {
+	return func(o *Options) {
+		o.UpgradeFunc = upgrade
+	}
+}
+
+// WithDisableEnvVar disables update checks when the environment variable name is set
+// to a truthy value, in addition to <APP>_DISABLE_UPDATE_CHECK.
+func WithDisableEnvVar(name string) func(*Options) {
+	return func(o *Options) {
+		o.DisableEnvVar = name
+	}
+}
+
+// WithLogger routes diagnostic output and update messages to l rather than clio,
+// such as an adapter for zap or slog. Use DiscardLogger to silence them entirely.
+func WithLogger(l Logger) func(*Options) {
+	return func(o *Options) {
+		o.Logger = l
+	}
 }
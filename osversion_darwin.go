@@ -0,0 +1,15 @@
+package updatecheck
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// osVersion returns the macOS product version, such as "12.6.1".
+func osVersion() string {
+	out, err := exec.Command("sw_vers", "-productVersion").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
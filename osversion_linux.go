@@ -0,0 +1,15 @@
+package updatecheck
+
+import (
+	"os"
+	"strings"
+)
+
+// osVersion returns the running kernel release, such as "5.15.0-58-generic".
+func osVersion() string {
+	data, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !windows
+
+package updatecheck
+
+// osVersion is not implemented on this operating system.
+func osVersion() string {
+	return ""
+}
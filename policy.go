@@ -0,0 +1,40 @@
+package updatecheck
+
+// UpdatePolicy controls which new releases the user is notified about.
+type UpdatePolicy string
+
+const (
+	// AllReleases notifies about every new release, including new major versions.
+	AllReleases UpdatePolicy = ""
+	// SameMajor only notifies about new minor and patch releases of the current major version.
+	// This is useful for teams which qualify new major versions separately.
+	SameMajor UpdatePolicy = "same-major"
+	// SameMinor only notifies about new patch releases of the current minor version.
+	SameMinor UpdatePolicy = "same-minor"
+)
+
+// allows returns true if the policy allows the user to be notified about the latest version.
+// If either version can't be parsed, the notification is allowed.
+func (p UpdatePolicy) allows(log Logger, currentVersion, latestVersion string) bool {
+	if p == AllReleases || latestVersion == "" {
+		return true
+	}
+	current, err := parseVersion(currentVersion)
+	if err != nil {
+		log.Debugf("could not apply update policy: %s", err.Error())
+		return true
+	}
+	latest, err := parseVersion(latestVersion)
+	if err != nil {
+		log.Debugf("could not apply update policy: %s", err.Error())
+		return true
+	}
+
+	switch p {
+	case SameMajor:
+		return latest.Major == current.Major
+	case SameMinor:
+		return latest.Major == current.Major && latest.Minor == current.Minor
+	}
+	return true
+}
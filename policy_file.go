@@ -0,0 +1,137 @@
+package updatecheck
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Policy is an organization-mandated update policy. It is distributed
+// to machines by IT (such as via MDM) as a JSON document alongside a
+// detached Ed25519 signature, so that end users can read but not tamper with it.
+// Fields which are set override the application's own options.
+type Policy struct {
+	// Disabled turns off update checks entirely.
+	Disabled bool `json:"disabled,omitempty"`
+	// URL overrides the update checking endpoint.
+	URL string `json:"url,omitempty"`
+	// AdditionalURLs overrides the additional update checking endpoints.
+	AdditionalURLs []string `json:"additionalUrls,omitempty"`
+	// UpdatePolicy overrides which new releases users are notified about.
+	UpdatePolicy UpdatePolicy `json:"updatePolicy,omitempty"`
+	// CheckInterval overrides the minimum time between checks, as a duration such as "72h".
+	// It takes precedence over a Throttle or cron schedule configured by the application.
+	CheckInterval string `json:"checkInterval,omitempty"`
+}
+
+// validate returns an error if the policy's overrides are invalid. Only the fields which the
+// policy sets are checked, as the rest of the options have already been validated.
+func (p Policy) validate() (time.Duration, error) {
+	if p.URL != "" {
+		if err := validateURL(p.URL, false); err != nil {
+			return 0, fmt.Errorf("policy url: %w", err)
+		}
+	}
+	for _, u := range p.AdditionalURLs {
+		if err := validateURL(u, false); err != nil {
+			return 0, fmt.Errorf("policy additionalUrls: %w", err)
+		}
+	}
+	switch p.UpdatePolicy {
+	case AllReleases, SameMajor, SameMinor:
+	default:
+		return 0, fmt.Errorf("policy updatePolicy: unknown update policy %q", p.UpdatePolicy)
+	}
+	if p.CheckInterval == "" {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(p.CheckInterval)
+	if err != nil {
+		return 0, fmt.Errorf("policy checkInterval: %w", err)
+	}
+	if interval <= 0 {
+		return 0, fmt.Errorf("policy checkInterval: must be positive")
+	}
+	return interval, nil
+}
+
+// loadPolicy reads the policy at path and verifies it against the signature at path + ".sig",
+// which must contain the base64-encoded Ed25519 signature of the policy file.
+// If there is no policy file, it returns nil. A policy without a signature is an error.
+func loadPolicy(path string, publicKey ed25519.PublicKey) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sigData, err := os.ReadFile(path + ".sig")
+	if err != nil {
+		return nil, fmt.Errorf("reading policy signature: %w", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sigData)))
+	if err != nil {
+		return nil, fmt.Errorf("decoding policy signature: %w", err)
+	}
+	if !ed25519.Verify(publicKey, data, sig) {
+		return nil, errors.New("policy signature is invalid")
+	}
+
+	var p Policy
+	err = json.Unmarshal(data, &p)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// applyPolicy overrides the options with the signed policy, if one is configured.
+// A missing policy file is not an error. A policy which can't be verified is ignored.
+func applyPolicy(o Options) (Options, error) {
+	if o.PolicyPath == "" {
+		return o, nil
+	}
+	p, err := loadPolicy(o.PolicyPath, o.PolicyPublicKey)
+	if err != nil {
+		return o, err
+	}
+	if p == nil {
+		return o, nil
+	}
+	// validate the policy first so that a signed but mistaken policy can't break the check.
+	interval, err := p.validate()
+	if err != nil {
+		return o, err
+	}
+
+	if p.Disabled {
+		o.frequency = frequencyNever
+	}
+	pinned := map[string]bool{}
+	if p.URL != "" {
+		o.URL = p.URL
+		pinned[p.URL] = true
+	}
+	if p.AdditionalURLs != nil {
+		o.AdditionalURLs = p.AdditionalURLs
+		for _, u := range p.AdditionalURLs {
+			pinned[u] = true
+		}
+	}
+	o.pinned = pinned
+	if p.UpdatePolicy != AllReleases {
+		o.UpdatePolicy = p.UpdatePolicy
+	}
+	if interval > 0 {
+		o.CheckInterval = interval
+		o.Throttle = nil
+		o.CronSchedule = ""
+	}
+	return o, nil
+}
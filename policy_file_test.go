@@ -0,0 +1,215 @@
+package updatecheck
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writePolicy writes policy to a temporary file along with its signature made with key,
+// and returns the path of the policy.
+func writePolicy(t *testing.T, policy string, key ed25519.PrivateKey) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(path, []byte(policy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(policy)))
+	if err := os.WriteFile(path+".sig", []byte(sig+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func testKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub, priv
+}
+
+func TestLoadPolicy(t *testing.T) {
+	pub, priv := testKey(t)
+	otherPub, _ := testKey(t)
+	const policy = `{"url":"https://updates.example.com"}`
+
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T) string
+		key     ed25519.PublicKey
+		want    *Policy
+		wantErr bool
+	}{
+		{
+			name:  "valid",
+			setup: func(t *testing.T) string { return writePolicy(t, policy, priv) },
+			key:   pub,
+			want:  &Policy{URL: "https://updates.example.com"},
+		},
+		{
+			name: "no policy",
+			setup: func(t *testing.T) string {
+				return filepath.Join(t.TempDir(), "policy.json")
+			},
+			key: pub,
+		},
+		{
+			name: "tampered",
+			setup: func(t *testing.T) string {
+				path := writePolicy(t, policy, priv)
+				if err := os.WriteFile(path, []byte(`{"url":"https://evil.example.com"}`), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return path
+			},
+			key:     pub,
+			wantErr: true,
+		},
+		{
+			name: "missing signature",
+			setup: func(t *testing.T) string {
+				path := writePolicy(t, policy, priv)
+				if err := os.Remove(path + ".sig"); err != nil {
+					t.Fatal(err)
+				}
+				return path
+			},
+			key:     pub,
+			wantErr: true,
+		},
+		{
+			name: "malformed signature",
+			setup: func(t *testing.T) string {
+				path := writePolicy(t, policy, priv)
+				if err := os.WriteFile(path+".sig", []byte("not base64!"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return path
+			},
+			key:     pub,
+			wantErr: true,
+		},
+		{
+			name:    "wrong key",
+			setup:   func(t *testing.T) string { return writePolicy(t, policy, priv) },
+			key:     otherPub,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadPolicy(tt.setup(t), tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && got.URL != tt.want.URL) {
+				t.Fatalf("loadPolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPolicy(t *testing.T) {
+	pub, priv := testKey(t)
+
+	tests := []struct {
+		name    string
+		policy  string
+		opts    []func(*Options)
+		wantErr bool
+		check   func(t *testing.T, o Options)
+	}{
+		{
+			name:   "cron schedule",
+			policy: `{"url":"https://updates.example.com"}`,
+			opts:   []func(*Options){WithCronSchedule("0 9 * * MON")},
+			check: func(t *testing.T, o Options) {
+				if o.URL != "https://updates.example.com" {
+					t.Errorf("URL = %q", o.URL)
+				}
+				if o.Throttle == nil {
+					t.Error("the cron throttle was removed")
+				}
+			},
+		},
+		{
+			name:   "custom client",
+			policy: `{"url":"https://updates.example.com"}`,
+			opts:   []func(*Options){func(o *Options) { o.Client = &http.Client{} }},
+			check: func(t *testing.T, o Options) {
+				if o.URL != "https://updates.example.com" {
+					t.Errorf("URL = %q", o.URL)
+				}
+			},
+		},
+		{
+			name:   "check interval overrides cron schedule",
+			policy: `{"checkInterval":"72h"}`,
+			opts:   []func(*Options){WithCronSchedule("0 9 * * MON")},
+			check: func(t *testing.T, o Options) {
+				if o.CheckInterval != 72*time.Hour {
+					t.Errorf("CheckInterval = %s, want 72h", o.CheckInterval)
+				}
+				if o.Throttle != nil || o.CronSchedule != "" {
+					t.Error("the cron schedule was not overridden")
+				}
+			},
+		},
+		{
+			name:   "disabled",
+			policy: `{"disabled":true}`,
+			check: func(t *testing.T, o Options) {
+				if o.frequency != frequencyNever {
+					t.Errorf("frequency = %v, want never", o.frequency)
+				}
+			},
+		},
+		{
+			name:    "invalid url",
+			policy:  `{"url":"ftp://updates.example.com"}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid check interval",
+			policy:  `{"checkInterval":"soon"}`,
+			wantErr: true,
+		},
+		{
+			name:    "negative check interval",
+			policy:  `{"checkInterval":"-1h"}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown update policy",
+			policy:  `{"updatePolicy":"sometimes"}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writePolicy(t, tt.policy, priv)
+			opts := append([]func(*Options){WithSignedPolicy(path, pub)}, tt.opts...)
+			o, err := NewOptions(true, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := applyPolicy(o)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if got.URL != o.URL || got.CheckInterval != o.CheckInterval {
+					t.Error("the options were changed by an invalid policy")
+				}
+				return
+			}
+			tt.check(t, got)
+		})
+	}
+}
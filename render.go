@@ -0,0 +1,156 @@
+package updatecheck
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// Toggle is a rendering setting which is either detected
+// from the environment or explicitly overridden.
+type Toggle int
+
+const (
+	// ToggleAuto detects the setting from the environment.
+	ToggleAuto Toggle = iota
+	// ToggleOn forces the setting on.
+	ToggleOn
+	// ToggleOff forces the setting off.
+	ToggleOff
+)
+
+// toggle returns ToggleOn or ToggleOff for an enabled flag.
+func toggle(enabled bool) Toggle {
+	if enabled {
+		return ToggleOn
+	}
+	return ToggleOff
+}
+
+// enabled resolves the toggle, calling detect if it is ToggleAuto.
+func (t Toggle) enabled(detect func() bool) bool {
+	switch t {
+	case ToggleOn:
+		return true
+	case ToggleOff:
+		return false
+	}
+	return detect()
+}
+
+// detectASCII returns true if the terminal is unlikely to be able to display unicode,
+// based on the TERM variable and the locale.
+func detectASCII() bool {
+	if os.Getenv("TERM") == "dumb" {
+		return true
+	}
+	if runtime.GOOS == "windows" {
+		return false
+	}
+	// the first of these variables which is set determines the character encoding.
+	for _, v := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		locale := os.Getenv(v)
+		if locale == "" {
+			continue
+		}
+		locale = strings.ToLower(locale)
+		return !strings.Contains(locale, "utf-8") && !strings.Contains(locale, "utf8")
+	}
+	return false
+}
+
+// detectHighContrast returns true if the terminal has asked for no colours.
+func detectHighContrast() bool {
+	_, noColor := os.LookupEnv("NO_COLOR")
+	return noColor || os.Getenv("TERM") == "dumb"
+}
+
+// asciiReplacements maps common unicode characters to ASCII equivalents.
+var asciiReplacements = strings.NewReplacer(
+	"─", "-", "━", "-", "│", "|", "┃", "|",
+	"┌", "+", "┐", "+", "└", "+", "┘", "+",
+	"╭", "+", "╮", "+", "╰", "+", "╯", "+",
+	"├", "+", "┤", "+", "┬", "+", "┴", "+", "┼", "+",
+	"→", "->", "←", "<-", "↑", "^", "↓", "v",
+	"•", "*", "…", "...", "–", "-", "—", "-",
+	"“", `"`, "”", `"`, "‘", "'", "’", "'",
+	"✔", "[ok]", "✓", "[ok]", "✘", "[x]", "✗", "[x]",
+)
+
+// toASCII replaces common unicode characters with ASCII equivalents, and any remaining
+// non-ASCII symbols, such as box drawing characters and emoji, with '?'.
+// Non-ASCII letters are kept, so that names and translated text remain readable.
+func toASCII(msg string) string {
+	msg = asciiReplacements.Replace(msg)
+	return strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII && unicode.IsSymbol(r) {
+			return '?'
+		}
+		return r
+	}, msg)
+}
+
+// toSingleLine collapses a message into a single line of plain text
+// so that screen readers don't read decorations aloud.
+// Symbols such as box drawing characters, emoji and bullets are removed,
+// and lines containing only punctuation (such as "-----") are dropped.
+func toSingleLine(msg string) string {
+	msg = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.So, r) || r == '•' {
+			return -1
+		}
+		return r
+	}, msg)
+
+	var parts []string
+	for _, line := range strings.Split(msg, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if strings.IndexFunc(line, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) == -1 {
+			continue
+		}
+		parts = append(parts, line)
+	}
+	return strings.Join(parts, " ")
+}
+
+// printMessage displays a message from the update service with the configured Logger,
+// according to the rendering options.
+func printMessage(msg string, severity Severity, o Options) {
+	var b strings.Builder
+	fprintMessage(&b, msg, severity, o)
+	text := strings.TrimSuffix(b.String(), "\n")
+	if severity.warning() {
+		warn(o.log(), "%s", text)
+		return
+	}
+	o.log().Infof("%s", text)
+}
+
+// fprintMessage writes a message from the update service to w without colours,
+// according to the rendering options.
+func fprintMessage(w io.Writer, msg string, severity Severity, o Options) {
+	if o.SingleLine {
+		msg = toSingleLine(msg)
+		if o.ASCII.enabled(detectASCII) {
+			msg = toASCII(msg)
+		}
+		fmt.Fprintln(w, msg)
+		return
+	}
+	if o.ASCII.enabled(detectASCII) {
+		msg = toASCII(msg)
+	}
+	if o.HighContrast.enabled(detectHighContrast) {
+		// use a text indicator rather than relying on colour.
+		indicator := "[i]"
+		if severity.warning() {
+			indicator = "[!]"
+		}
+		fmt.Fprintf(w, "%s %s\n", indicator, msg)
+		return
+	}
+	fmt.Fprintln(w, msg)
+}
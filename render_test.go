@@ -0,0 +1,117 @@
+package updatecheck
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToASCII(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{msg: "╭──────╮\n│ v0.21.0 │\n╰──────╯", want: "+------+\n| v0.21.0 |\n+------+"},
+		{msg: "v0.20.0 → v0.21.0", want: "v0.20.0 -> v0.21.0"},
+		{msg: "Update available 🎉", want: "Update available ?"},
+		{msg: "═══ notice ═══", want: "??? notice ???"},
+		{msg: "Neue Version für Zürich", want: "Neue Version für Zürich"},
+		{msg: "新しいバージョンがあります", want: "新しいバージョンがあります"},
+		{msg: "plain ASCII", want: "plain ASCII"},
+	}
+	for _, tt := range tests {
+		if got := toASCII(tt.msg); got != tt.want {
+			t.Errorf("toASCII(%q) = %q, want %q", tt.msg, got, tt.want)
+		}
+	}
+}
+
+// captureOutput returns what fn writes to stdout and stderr.
+func captureOutput(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+	out := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- b
+	}()
+	fn()
+	w.Close()
+	return string(<-out)
+}
+
+func TestPrintUsesLogger(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		opts []func(*Options)
+		want []string
+	}{
+		{name: "default", opts: []func(*Options){WithHighContrast(false)}, want: []string{"A new version is available: v0.21.0"}},
+		{name: "NO_COLOR", env: "NO_COLOR", want: []string{"[i] A new version is available: v0.21.0"}},
+		{name: "high contrast", opts: []func(*Options){WithHighContrast(true)}, want: []string{"[i] A new version is available: v0.21.0"}},
+		{name: "single line", opts: []func(*Options){WithSingleLine()}, want: []string{"A new version is available: v0.21.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateState(t)
+			if tt.env != "" {
+				t.Setenv(tt.env, "1")
+			}
+			srv := updateServer(t, "v0.21.0")
+
+			for _, logger := range []Logger{DiscardLogger, &recordingLogger{}} {
+				opts := append(testOptions(srv.URL, WithLogger(logger), WithASCII(false)), tt.opts...)
+				c, err := New(testApp, "v0.20.0", opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				c.Check()
+				if out := captureOutput(t, c.Print); out != "" {
+					t.Errorf("Print() wrote %q directly rather than using the logger", out)
+				}
+				if l, ok := logger.(*recordingLogger); ok && !reflect.DeepEqual(l.info, tt.want) {
+					t.Errorf("logged %q, want %q", l.info, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSingleLine(t *testing.T) {
+	res := staticBackend{
+		UpdateRequired: true,
+		LatestVersion:  "v0.21.0",
+		Message:        "╭──────────╮\n│ 🎉 A new version is available: v0.21.0 │\n╰──────────╯",
+		Health:         ReleaseStabilizing,
+		Changelog:      []Release{{Version: "v0.21.0", Notes: "- Faster assume\n- Fixed → arrows"}},
+		Messages:       []Notice{{Severity: SeverityWarning, Message: "v0.20.0 is deprecated"}},
+	}
+	for _, ascii := range []bool{false, true} {
+		isolateState(t)
+		got := runCheck(t, GrantedCLI, "v0.20.0", testOptions("https://update.example.com",
+			WithBackend(res), WithSingleLine(), WithASCII(ascii), WithChangelog(5))...)
+		if !strings.HasSuffix(got, "\n") || strings.Count(got, "\n") != 1 {
+			t.Errorf("ascii=%v: output isn't a single line:\n%s", ascii, got)
+		}
+		for _, want := range []string{"A new version is available: v0.21.0", "Faster assume", "stabilizing", "deprecated"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("ascii=%v: output is missing %q:\n%s", ascii, want, got)
+			}
+		}
+		if strings.ContainsAny(got, "•╭│🎉") {
+			t.Errorf("ascii=%v: output contains decorations:\n%s", ascii, got)
+		}
+		if ascii && strings.IndexFunc(got, func(r rune) bool { return r > 127 }) != -1 {
+			t.Errorf("output isn't ASCII:\n%s", got)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package updatecheck
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExitCodeUpdateRequired is the exit code suggested for CLIs which refuse to run
+// because an update is required.
+const ExitCodeUpdateRequired = 3
+
+// RequiredUpdateError is returned by RequiredUpdate() when the server has marked
+// the running version as one which must be upgraded, such as a version with a
+// known data-corrupting bug.
+type RequiredUpdateError struct {
+	// App is the application which must be upgraded.
+	App App
+	// CurrentVersion is the running version.
+	CurrentVersion string
+	// LatestVersion is the latest available version, if the server provides it.
+	LatestVersion string
+	// Message is the server's explanation of why the update is required.
+	Message string
+	// UpgradeCommand is the command to upgrade with, if one is configured for the install method.
+	UpgradeCommand string
+}
+
+func (e *RequiredUpdateError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s must be upgraded before it can be used", e.App, e.CurrentVersion)
+	if e.LatestVersion != "" {
+		fmt.Fprintf(&b, " (the latest version is %s)", e.LatestVersion)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	if e.UpgradeCommand != "" {
+		fmt.Fprintf(&b, ". To upgrade, run: %s", e.UpgradeCommand)
+	}
+	return b.String()
+}
+
+// ExitCode returns the exit code which the CLI should exit with, ExitCodeUpdateRequired.
+func (e *RequiredUpdateError) ExitCode() int {
+	return ExitCodeUpdateRequired
+}
+
+// RequiredUpdate waits for the most recent check to finish and returns a *RequiredUpdateError
+// if the server requires the running version to be upgraded, or nil otherwise.
+// Whether to refuse to run is left to the calling CLI, which can print the error and
+// exit with its ExitCode().
+//
+// If response signing is enabled, only signed responses can require an update.
+func (c *Checker) RequiredUpdate() error {
+	c.mu.Lock()
+	h := c.current
+	c.mu.Unlock()
+	if h == nil {
+		return nil
+	}
+	<-h.done
+	for _, msg := range h.msgs {
+		if !msg.required {
+			continue
+		}
+		return &RequiredUpdateError{
+			App:            c.app,
+			CurrentVersion: c.currentVersion,
+			LatestVersion:  msg.latestVersion,
+			Message:        withoutIndicator(msg.text),
+			UpgradeCommand: c.opts.upgradeCommand(),
+		}
+	}
+	return nil
+}
+
+// IsUpdateRequired waits for the most recent check to finish and returns true
+// if the server requires the running version to be upgraded.
+func (c *Checker) IsUpdateRequired() bool {
+	return c.RequiredUpdate() != nil
+}
+
+// RequiredUpdate waits for the checks started by Check() to finish and returns a
+// *RequiredUpdateError for the first application which the server requires to be upgraded.
+func RequiredUpdate() error {
+	for _, c := range pendingCheckers() {
+		if err := c.RequiredUpdate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsUpdateRequired waits for the checks started by Check() to finish and returns
+// true if the server requires any of the applications to be upgraded.
+func IsUpdateRequired() bool {
+	return RequiredUpdate() != nil
+}
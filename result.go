@@ -0,0 +1,147 @@
+package updatecheck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Severity is how important an update message is.
+type Severity string
+
+const (
+	// SeverityInfo is an informational notice.
+	SeverityInfo Severity = "info"
+	// SeverityUpdate is an ordinary update notice.
+	SeverityUpdate Severity = "update"
+	// SeverityWarning is a notice which the user should act on, such as a deprecation.
+	// Warnings are displayed with the logger's Warnf, if it has one.
+	SeverityWarning Severity = "warning"
+	// SeverityCritical is a security advisory. Security advisories are displayed as warnings,
+	// and are shown even when the user has snoozed update messages.
+	SeverityCritical Severity = "critical"
+)
+
+// severityRanks orders the severities from least to most important.
+var severityRanks = map[Severity]int{
+	SeverityInfo:     0,
+	SeverityUpdate:   1,
+	SeverityWarning:  2,
+	SeverityCritical: 3,
+}
+
+// normalize returns the severity, treating unknown severities from the server as SeverityInfo.
+func (s Severity) normalize() Severity {
+	if _, ok := severityRanks[s]; !ok {
+		return SeverityInfo
+	}
+	return s
+}
+
+// higher returns the more important of the two severities.
+func (s Severity) higher(other Severity) Severity {
+	if severityRanks[other.normalize()] > severityRanks[s.normalize()] {
+		return other
+	}
+	return s
+}
+
+// warning returns true if the severity should be displayed as a warning.
+func (s Severity) warning() bool {
+	return s == SeverityWarning || s == SeverityCritical
+}
+
+// ReleaseHealth is the server's advice about the stability of a release.
+type ReleaseHealth string
+
+const (
+	// ReleaseHealthy means that the release is considered stable.
+	ReleaseHealthy ReleaseHealth = "healthy"
+	// ReleaseStabilizing means that the release is new and still being monitored,
+	// for example because its crash rate is elevated, so cautious users may want
+	// to hold off upgrading.
+	ReleaseStabilizing ReleaseHealth = "stabilizing"
+)
+
+// Result is the result of a check run with CheckNow.
+type Result struct {
+	// UpdateRequired is true if there is a new version available.
+	UpdateRequired bool `json:"updateRequired"`
+	// LatestVersion is the latest available version, if the server provides it.
+	LatestVersion string `json:"latestVersion,omitempty"`
+	// Message is the text which Print() would display, with each message on its own line.
+	Message string `json:"message,omitempty"`
+	// Severity is the highest severity of the messages.
+	Severity Severity `json:"severity"`
+	// Health is the server's advice about the stability of the latest version, if it provided any.
+	Health ReleaseHealth `json:"health,omitempty"`
+	// Actions are optional actions for the messages, which can be run with RunAction.
+	Actions []Action `json:"actions,omitempty"`
+	// Required is true if the server requires the running version to be upgraded.
+	Required bool `json:"required,omitempty"`
+	// Changelog is the release notes for the versions since the running version,
+	// if the server provides them.
+	Changelog []Release `json:"changelog,omitempty"`
+}
+
+// CheckNow checks for updates to app immediately, ignoring throttling, and returns the result
+// rather than queueing a message for Print(). This allows the calling CLI to drive its own UI,
+// such as a prompt or JSON output, from the result.
+//
+// Checks disabled by environment variables or policy are not run, and an error is returned
+// describing why, as it is if the check fails.
+func CheckNow(ctx context.Context, app App, currentVersion string, prod bool, opts ...func(*Options)) (*Result, error) {
+	o, err := NewOptions(prod, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	freq, envVar := frequencyFromEnv(app, o.DisableEnvVar)
+	if freq == frequencyNever {
+		decide(app, o, DecisionSkippedEnvVar, envVar+" env var disables update checks", false)
+		return nil, fmt.Errorf("update checks are disabled by the %s env var", envVar)
+	}
+	o.frequency = frequencyAlways
+
+	// capture the final decision, while still passing it to the host application's hook.
+	var cd CheckDecision
+	hook := o.OnDecision
+	o.OnDecision = func(d CheckDecision) {
+		cd = d
+		if hook != nil {
+			hook(d)
+		}
+	}
+
+	c := &check{app: app, opts: o, done: make(chan struct{})}
+	doCheck(ctx, c, currentVersion, o)
+	if cd.Decision != DecisionPerformed {
+		return nil, fmt.Errorf("update check %s: %s", cd.Decision, cd.Detail)
+	}
+
+	res := resultFrom(c.msgs)
+	res.UpdateRequired = cd.UpdateFound
+	return &res, nil
+}
+
+// resultFrom summarises the messages from a check.
+func resultFrom(msgs []message) Result {
+	res := Result{Severity: SeverityInfo}
+	var lines []string
+	for _, msg := range msgs {
+		if msg.text != "" {
+			lines = append(lines, msg.text)
+		}
+		if res.LatestVersion == "" {
+			res.LatestVersion = msg.latestVersion
+			res.Health = msg.health
+		}
+		res.UpdateRequired = res.UpdateRequired || msg.update
+		res.Actions = append(res.Actions, msg.actions...)
+		res.Severity = res.Severity.higher(msg.severity)
+		res.Required = res.Required || msg.required
+		res.Changelog = append(res.Changelog, msg.changelog...)
+	}
+	res.Message = strings.Join(lines, "\n")
+	return res
+}
@@ -0,0 +1,88 @@
+package updatecheck
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxMessageLength is the maximum number of characters of a server-provided
+// message which are displayed. Longer messages are truncated.
+const maxMessageLength = 1000
+
+// sanitizeMessage removes terminal escape sequences and control characters
+// from a server-provided message, so that a compromised or buggy server
+// can't change the terminal title, write to the clipboard or otherwise
+// inject escape sequences into the user's shell.
+// Newlines and tabs are kept, and the message is capped at maxMessageLength characters.
+func sanitizeMessage(msg string) string {
+	var b strings.Builder
+	runes := []rune(msg)
+	n := 0
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\x1b' && i+1 < len(runes):
+			i = skipEscape(runes, i+1)
+			continue
+		case r == '\u009b':
+			// 8-bit CSI
+			i = skipCSI(runes, i+1)
+			continue
+		case r == '\u0090' || r == '\u0098' || r == '\u009d' || r == '\u009e' || r == '\u009f':
+			// 8-bit DCS, SOS, OSC, PM and APC, which are terminated by a string terminator.
+			i = skipString(runes, i+1)
+			continue
+		case r == '\n' || r == '\t':
+		case unicode.IsControl(r):
+			continue
+		}
+		if n == maxMessageLength {
+			b.WriteString("...")
+			break
+		}
+		b.WriteRune(r)
+		n++
+	}
+	return b.String()
+}
+
+// skipEscape skips the escape sequence whose introducer is at runes[i],
+// returning the index of the last rune of the sequence.
+func skipEscape(runes []rune, i int) int {
+	switch runes[i] {
+	case '[':
+		return skipCSI(runes, i+1)
+	case ']', 'P', 'X', '^', '_':
+		// OSC (such as setting the terminal title or clipboard), DCS, SOS, PM and APC.
+		return skipString(runes, i+1)
+	}
+	// a two character escape sequence.
+	return i
+}
+
+// skipCSI skips the parameters of a control sequence starting at runes[i],
+// returning the index of its final byte.
+func skipCSI(runes []rune, i int) int {
+	for ; i < len(runes); i++ {
+		if runes[i] >= 0x40 && runes[i] <= 0x7e {
+			return i
+		}
+	}
+	return len(runes) - 1
+}
+
+// skipString skips a control string starting at runes[i], returning the
+// index of the end of its terminator (BEL, ESC \ or ST).
+func skipString(runes []rune, i int) int {
+	for ; i < len(runes); i++ {
+		switch runes[i] {
+		case '\a', '\u009c':
+			return i
+		case '\x1b':
+			if i+1 < len(runes) && runes[i+1] == '\\' {
+				return i + 1
+			}
+		}
+	}
+	return len(runes) - 1
+}
@@ -0,0 +1,133 @@
+package updatecheck
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// version is a parsed semantic version, such as "v1.2.3-beta.1+abc".
+type version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease string
+	Build      string
+}
+
+// parseVersion parses a semantic version. The leading "v" is optional,
+// and the minor and patch components may be omitted.
+func parseVersion(s string) (version, error) {
+	var v version
+	in := strings.TrimPrefix(strings.TrimSpace(s), "v")
+
+	if i := strings.IndexByte(in, '+'); i != -1 {
+		v.Build = in[i+1:]
+		in = in[:i]
+	}
+	if i := strings.IndexByte(in, '-'); i != -1 {
+		v.Prerelease = in[i+1:]
+		in = in[:i]
+	}
+
+	parts := strings.Split(in, ".")
+	if len(parts) > 3 {
+		return version{}, fmt.Errorf("invalid version %q: too many components", s)
+	}
+	nums := []*uint64{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return version{}, fmt.Errorf("invalid version %q: %w", s, err)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+// compare returns -1 if v is older than other, 1 if it is newer and 0 if they
+// have the same precedence, following the semver 2.0 rules.
+// Pre-releases are older than the release they precede, and build metadata is ignored.
+func (v version) compare(other version) int {
+	for _, pair := range [][2]uint64{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		if pair[0] < pair[1] {
+			return -1
+		}
+		if pair[0] > pair[1] {
+			return 1
+		}
+	}
+
+	switch {
+	case v.Prerelease == other.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case other.Prerelease == "":
+		return -1
+	}
+
+	a, b := strings.Split(v.Prerelease, "."), strings.Split(other.Prerelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareIdentifier(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	// a larger set of pre-release fields has a higher precedence if all of the preceding fields are equal.
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
+
+// compareIdentifier compares a single dot-separated pre-release identifier.
+// Numeric identifiers are compared numerically and have lower precedence than alphanumeric ones.
+func compareIdentifier(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		if an < bn {
+			return -1
+		}
+		if an > bn {
+			return 1
+		}
+		return 0
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// compareLocally decides whether an update is required by comparing the current version
+// with the latest version in r, rather than relying on the server's decision.
+// This allows simple static endpoints which only return the latest version to be used.
+// A default message is only added if an update is required and the server didn't send one,
+// and messages from the server, such as advisories, are always kept.
+func compareLocally(log Logger, app App, currentVersion string, r Response) Response {
+	if r.LatestVersion == "" {
+		return r
+	}
+	current, err := parseVersion(currentVersion)
+	if err != nil {
+		log.Debugf("could not compare versions locally: %s", err.Error())
+		return r
+	}
+	latest, err := parseVersion(r.LatestVersion)
+	if err != nil {
+		log.Debugf("could not compare versions locally: %s", err.Error())
+		return r
+	}
+
+	r.UpdateRequired = current.compare(latest) < 0
+	if r.UpdateRequired && r.Message == "" {
+		r.Message = fmt.Sprintf("A new version of %s is available: %s (you have %s)", app, r.LatestVersion, currentVersion)
+	}
+	return r
+}
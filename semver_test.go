@@ -0,0 +1,49 @@
+package updatecheck
+
+import "testing"
+
+func TestCompareLocally(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		current     string
+		r           Response
+		wantUpdate  bool
+		wantMessage string
+	}{
+		{
+			name:        "update with default message",
+			current:     "v0.20.0",
+			r:           Response{LatestVersion: "v0.21.0"},
+			wantUpdate:  true,
+			wantMessage: "A new version of granted-cli is available: v0.21.0 (you have v0.20.0)",
+		},
+		{
+			name:        "update with server message",
+			current:     "v0.20.0",
+			r:           Response{LatestVersion: "v0.21.0", Message: "upgrade now"},
+			wantUpdate:  true,
+			wantMessage: "upgrade now",
+		},
+		{
+			name:        "advisory is kept when up to date",
+			current:     "v0.21.0",
+			r:           Response{LatestVersion: "v0.21.0", UpdateRequired: true, Message: "v0.19 is deprecated"},
+			wantMessage: "v0.19 is deprecated",
+		},
+		{
+			name:    "no default message when up to date",
+			current: "v0.21.0",
+			r:       Response{LatestVersion: "v0.21.0"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := compareLocally(DiscardLogger, GrantedCLI, tc.current, tc.r)
+			if got.UpdateRequired != tc.wantUpdate {
+				t.Errorf("UpdateRequired = %v, want %v", got.UpdateRequired, tc.wantUpdate)
+			}
+			if got.Message != tc.wantMessage {
+				t.Errorf("Message = %q, want %q", got.Message, tc.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package updatecheck
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+)
+
+// signatureHeader contains the base64-encoded Ed25519 signature of the signed payload.
+const signatureHeader = "X-Signature"
+
+// nonceHeader contains a random value sent with each request when responses are signed,
+// which the signature must include so that an old signed response can't be replayed.
+const nonceHeader = "X-Updatecheck-Nonce"
+
+// newNonce returns a random value for the nonce header.
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// signedPayload returns the data which the update service signs: the response body bound to
+// the application, version and nonce of the request, so that a response for another request
+// doesn't verify. It is "updatecheck-response-v1\n<application>\n<version>\n<nonce>\n<body>".
+func signedPayload(app App, version string, nonce string, body []byte) []byte {
+	header := "updatecheck-response-v1\n" + string(app) + "\n" + version + "\n" + nonce + "\n"
+	return append([]byte(header), body...)
+}
+
+// verifiedIndicator is shown before messages with a valid signature.
+const verifiedIndicator = "✓ "
+
+// verifyResponse returns true if sig is a valid signature of payload.
+func verifyResponse(log Logger, publicKey ed25519.PublicKey, payload []byte, sig string) bool {
+	if sig == "" {
+		log.Debugf("update check response is not signed")
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(sig))
+	if err != nil {
+		log.Debugf("decoding update check response signature: %s", err.Error())
+		return false
+	}
+	if !ed25519.Verify(publicKey, payload, decoded) {
+		log.Debugf("update check response signature is invalid")
+		return false
+	}
+	return true
+}
+
+// withoutIndicator removes a verified indicator from the start of an unsigned message,
+// so that a message can't claim to be signed.
+func withoutIndicator(msg string) string {
+	return strings.TrimLeft(msg, "✓✔ ")
+}
@@ -0,0 +1,153 @@
+package updatecheck
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// signingServer returns an update service which signs its response with sign,
+// given the request and the response body.
+func signingServer(t *testing.T, body string, sign func(r *http.Request, req Request, body []byte) string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		if sig := sign(r, req, []byte(body)); sig != "" {
+			w.Header().Set(signatureHeader, sig)
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSignedResponses(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(key ed25519.PrivateKey, payload []byte) string {
+		return base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload))
+	}
+	const body = `{"updateRequired":true,"latestVersion":"v0.21.0","message":"update to v0.21.0"}`
+
+	tests := []struct {
+		name string
+		sign func(r *http.Request, req Request, body []byte) string
+		want string
+	}{
+		{
+			name: "valid",
+			sign: func(r *http.Request, req Request, body []byte) string {
+				return sign(priv, signedPayload(req.Application, req.Version, r.Header.Get(nonceHeader), body))
+			},
+			want: verifiedIndicator + "update to v0.21.0\n",
+		},
+		{
+			name: "unsigned",
+			sign: func(*http.Request, Request, []byte) string { return "" },
+			want: "update to v0.21.0\n",
+		},
+		{
+			name: "malformed signature",
+			sign: func(*http.Request, Request, []byte) string { return "not base64!" },
+			want: "update to v0.21.0\n",
+		},
+		{
+			name: "wrong key",
+			sign: func(r *http.Request, req Request, body []byte) string {
+				return sign(otherKey, signedPayload(req.Application, req.Version, r.Header.Get(nonceHeader), body))
+			},
+			want: "update to v0.21.0\n",
+		},
+		{
+			name: "tampered body",
+			sign: func(r *http.Request, req Request, body []byte) string {
+				tampered := []byte(strings.Replace(string(body), "v0.21.0", "v0.22.0", -1))
+				return sign(priv, signedPayload(req.Application, req.Version, r.Header.Get(nonceHeader), tampered))
+			},
+			want: "update to v0.21.0\n",
+		},
+		{
+			name: "body only",
+			sign: func(r *http.Request, req Request, body []byte) string {
+				return sign(priv, body)
+			},
+			want: "update to v0.21.0\n",
+		},
+		{
+			name: "replayed from another request",
+			sign: func(r *http.Request, req Request, body []byte) string {
+				return sign(priv, signedPayload(req.Application, req.Version, "an-earlier-nonce", body))
+			},
+			want: "update to v0.21.0\n",
+		},
+		{
+			name: "replayed for another version",
+			sign: func(r *http.Request, req Request, body []byte) string {
+				return sign(priv, signedPayload(req.Application, "v0.19.0", r.Header.Get(nonceHeader), body))
+			},
+			want: "update to v0.21.0\n",
+		},
+		{
+			name: "replayed for another application",
+			sign: func(r *http.Request, req Request, body []byte) string {
+				return sign(priv, signedPayload(testApp, req.Version, r.Header.Get(nonceHeader), body))
+			},
+			want: "update to v0.21.0\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateState(t)
+			srv := signingServer(t, body, tt.sign)
+			got := runCheck(t, GrantedCLI, "v0.20.0", testOptions(srv.URL, WithSignedResponses(pub))...)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignedResponseNonce(t *testing.T) {
+	isolateState(t)
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var nonces []string
+	srv := signingServer(t, `{}`, func(r *http.Request, req Request, body []byte) string {
+		mu.Lock()
+		defer mu.Unlock()
+		nonces = append(nonces, r.Header.Get(nonceHeader))
+		return ""
+	})
+	runCheck(t, GrantedCLI, "v0.20.0", testOptions(srv.URL, WithSignedResponses(pub))...)
+	runCheck(t, GrantedCLI, "v0.20.0", testOptions(srv.URL, WithSignedResponses(pub))...)
+	runCheck(t, GrantedCLI, "v0.20.0", testOptions(srv.URL)...)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(nonces) != 3 {
+		t.Fatalf("got %d requests, want 3", len(nonces))
+	}
+	if nonces[0] == "" || nonces[0] == nonces[1] {
+		t.Errorf("nonces = %q, want a different nonce for each request", nonces[:2])
+	}
+	if nonces[2] != "" {
+		t.Errorf("a nonce was sent without WithSignedResponses: %q", nonces[2])
+	}
+}
@@ -0,0 +1,47 @@
+package updatecheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ReleaseSnapshot is release metadata compiled into the binary, such as with go:embed,
+// so that extreme staleness can be detected without reaching the update service.
+type ReleaseSnapshot struct {
+	// LatestVersion is the latest version which was known when the binary was built.
+	LatestVersion string `json:"latestVersion"`
+	// ReleaseDate is when LatestVersion was released.
+	ReleaseDate time.Time `json:"releaseDate"`
+}
+
+// parseReleaseSnapshot parses the JSON release metadata provided with WithReleaseSnapshot().
+func parseReleaseSnapshot(data []byte) (ReleaseSnapshot, error) {
+	var rs ReleaseSnapshot
+	err := json.Unmarshal(data, &rs)
+	if err != nil {
+		return ReleaseSnapshot{}, err
+	}
+	if rs.ReleaseDate.IsZero() {
+		return ReleaseSnapshot{}, fmt.Errorf("releaseDate is required")
+	}
+	return rs, nil
+}
+
+// snapshotMessage returns a warning that the release metadata compiled into the binary
+// is older than the maximum age, or an empty string if it isn't or there is no snapshot.
+func snapshotMessage(app App, o Options, now time.Time) string {
+	if len(o.ReleaseSnapshot) == 0 {
+		return ""
+	}
+	// the snapshot has already been validated.
+	rs, _ := parseReleaseSnapshot(o.ReleaseSnapshot)
+	age := now.Sub(rs.ReleaseDate)
+	if age < o.ReleaseSnapshotMaxAge {
+		return ""
+	}
+	if rs.LatestVersion == "" {
+		return fmt.Sprintf("The newest release known to this build of %s is %s old. Check for a newer version to get the latest fixes.", app, formatAge(age))
+	}
+	return fmt.Sprintf("The newest release known to this build of %s, %s, is %s old. Check for a newer version to get the latest fixes.", app, rs.LatestVersion, formatAge(age))
+}
@@ -0,0 +1,25 @@
+package updatecheck
+
+// stopChecking returns true if the response from the primary endpoint tells the running version
+// to stop checking for updates. Additional endpoints can't stop checks, as they may be run by
+// someone other than the update service, such as an internal mirror.
+// If response signing is enabled, only signed responses are followed.
+func stopChecking(responses []Response, o Options) bool {
+	signing := len(o.ResponsePublicKey) > 0
+	for _, r := range responses {
+		if !r.StopChecking {
+			continue
+		}
+		// a custom backend replaces the HTTP endpoints, so its response is the primary one.
+		if o.Backend == nil && r.endpoint != o.URL {
+			o.log().Debugf("ignoring directive to stop checking for updates from additional endpoint %s", r.endpoint)
+			continue
+		}
+		if signing && !r.verified {
+			o.log().Debugf("ignoring directive to stop checking for updates as the response is not signed")
+			continue
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package updatecheck
+
+import "testing"
+
+func TestStopChecking(t *testing.T) {
+	const primary, additional = "https://update.example.com", "https://mirror.example.com"
+	o := Options{URL: primary, AdditionalURLs: []string{additional}, Logger: DiscardLogger}
+
+	tests := []struct {
+		name      string
+		responses []Response
+		backend   Backend
+		want      bool
+	}{
+		{name: "primary", responses: []Response{{StopChecking: true, endpoint: primary}}, want: true},
+		{name: "additional", responses: []Response{{endpoint: primary}, {StopChecking: true, endpoint: additional}}},
+		{name: "additional when primary failed", responses: []Response{{StopChecking: true, endpoint: additional}}},
+		{name: "custom backend", responses: []Response{{StopChecking: true}}, backend: staticBackend{}, want: true},
+		{name: "not stopped", responses: []Response{{endpoint: primary}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := o
+			o.Backend = tt.backend
+			if got := stopChecking(tt.responses, o); got != tt.want {
+				t.Errorf("stopChecking() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package updatecheck
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxDiagnostics is the number of recent decisions and samples kept for support bundles.
+const maxDiagnostics = 20
+
+// diagnostics records recent activity in this process for support bundles.
+var diagnostics struct {
+	mu        sync.Mutex
+	decisions []CheckDecision
+	configs   map[App]resolvedConfig
+	samples   []sample
+}
+
+// resolvedConfig is the redacted configuration used for a check.
+type resolvedConfig struct {
+	URL              string        `json:"url"`
+	AdditionalURLs   []string      `json:"additionalUrls,omitempty"`
+	CustomClient     bool          `json:"customClient"`
+	Timeout          time.Duration `json:"timeout"`
+	Retries          int           `json:"retries"`
+	DialTimeout      time.Duration `json:"dialTimeout"`
+	DNSTimeout       time.Duration `json:"dnsTimeout"`
+	IPPreference     IPPreference  `json:"ipPreference"`
+	FallbackDelay    time.Duration `json:"fallbackDelay"`
+	DoHResolverURL   string        `json:"dohResolverUrl,omitempty"`
+	CollectorURL     string        `json:"collectorUrl,omitempty"`
+	IncludeOSVersion bool          `json:"includeOsVersion"`
+	MinimalTelemetry bool          `json:"minimalTelemetry"`
+	UpdatePolicy     UpdatePolicy  `json:"updatePolicy,omitempty"`
+	FirstCheckDelay  time.Duration `json:"firstCheckDelay"`
+	CheckInterval    time.Duration `json:"checkInterval"`
+	Jitter           float64       `json:"jitter"`
+	PolicyPath       string        `json:"policyPath,omitempty"`
+	Frequency        frequency     `json:"frequency"`
+	ProcessThrottle  bool          `json:"processThrottle"`
+	ImageMinAge      time.Duration `json:"imageMinAge,omitempty"`
+}
+
+// sample is a redacted request and response from an update checking endpoint.
+type sample struct {
+	Time     time.Time       `json:"time"`
+	URL      string          `json:"url"`
+	Request  json.RawMessage `json:"request,omitempty"`
+	Status   int             `json:"status,omitempty"`
+	Response string          `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// redactURL removes credentials and query parameters from u, as they may contain secrets.
+func redactURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "<invalid url>"
+	}
+	if parsed.User != nil {
+		parsed.User = url.User("redacted")
+	}
+	if parsed.RawQuery != "" {
+		parsed.RawQuery = "redacted"
+	}
+	return parsed.String()
+}
+
+// redactRequest removes the fields which could identify the user from a serialized Request.
+func redactRequest(body json.RawMessage) json.RawMessage {
+	var req Request
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil
+	}
+	if req.ClientID != "" {
+		req.ClientID = "redacted"
+	}
+	if req.OSVersion != "" {
+		req.OSVersion = "redacted"
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// redactState removes the anonymous client ID, credentials and query strings in endpoint URLs,
+// and cached response bodies from a version config file.
+func redactState(data []byte) ([]byte, error) {
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	if _, ok := state["clientId"]; ok {
+		state["clientId"] = json.RawMessage(`"redacted"`)
+	}
+	if raw, ok := state["endpoints"]; ok {
+		var endpoints map[string]string
+		if err := json.Unmarshal(raw, &endpoints); err != nil {
+			return nil, err
+		}
+		redacted := map[string]string{}
+		for from, to := range endpoints {
+			redacted[redactURL(from)] = redactURL(to)
+		}
+		b, err := json.Marshal(redacted)
+		if err != nil {
+			return nil, err
+		}
+		state["endpoints"] = b
+	}
+	if raw, ok := state["responses"]; ok {
+		var responses map[string]conditionalResponse
+		if err := json.Unmarshal(raw, &responses); err != nil {
+			return nil, err
+		}
+		redacted := map[string]conditionalResponse{}
+		for u, r := range responses {
+			if r.Body != "" {
+				r.Body = "redacted"
+			}
+			redacted[redactURL(u)] = r
+		}
+		b, err := json.Marshal(redacted)
+		if err != nil {
+			return nil, err
+		}
+		state["responses"] = b
+	}
+	return json.Marshal(state)
+}
+
+func recordDecision(d CheckDecision) {
+	diagnostics.mu.Lock()
+	defer diagnostics.mu.Unlock()
+	diagnostics.decisions = append(diagnostics.decisions, d)
+	if len(diagnostics.decisions) > maxDiagnostics {
+		diagnostics.decisions = diagnostics.decisions[1:]
+	}
+}
+
+func recordConfig(app App, o Options) {
+	var additional []string
+	for _, u := range o.AdditionalURLs {
+		additional = append(additional, redactURL(u))
+	}
+	rc := resolvedConfig{
+		URL:              redactURL(o.URL),
+		AdditionalURLs:   additional,
+		CustomClient:     o.Client != nil,
+		Timeout:          o.Timeout,
+		Retries:          o.Retries,
+		DialTimeout:      o.DialTimeout,
+		DNSTimeout:       o.DNSTimeout,
+		IPPreference:     o.IPPreference,
+		FallbackDelay:    o.FallbackDelay,
+		IncludeOSVersion: o.IncludeOSVersion,
+		MinimalTelemetry: o.MinimalTelemetry,
+		UpdatePolicy:     o.UpdatePolicy,
+		FirstCheckDelay:  o.FirstCheckDelay,
+		CheckInterval:    o.CheckInterval,
+		Jitter:           o.Jitter,
+		PolicyPath:       o.PolicyPath,
+		Frequency:        o.frequency,
+		ProcessThrottle:  o.ProcessThrottle,
+		ImageMinAge:      o.ImageMinAge,
+	}
+	if o.DoHResolverURL != "" {
+		rc.DoHResolverURL = redactURL(o.DoHResolverURL)
+	}
+	if o.CollectorURL != "" {
+		rc.CollectorURL = redactURL(o.CollectorURL)
+	}
+
+	diagnostics.mu.Lock()
+	defer diagnostics.mu.Unlock()
+	if diagnostics.configs == nil {
+		diagnostics.configs = map[App]resolvedConfig{}
+	}
+	diagnostics.configs[app] = rc
+}
+
+func recordSample(s sample) {
+	s.URL = redactURL(s.URL)
+	s.Request = redactRequest(s.Request)
+	diagnostics.mu.Lock()
+	defer diagnostics.mu.Unlock()
+	diagnostics.samples = append(diagnostics.samples, s)
+	if len(diagnostics.samples) > maxDiagnostics {
+		diagnostics.samples = diagnostics.samples[1:]
+	}
+}
+
+// WriteSupportBundle writes a zip archive to w containing the state files, resolved configuration,
+// recent check decisions and request and response samples for the update checks made by this
+// process, to help debug reports such as "updates never show". Credentials in URLs, the client ID
+// and the OS version are redacted.
+// Call Print() first to ensure that background checks have finished.
+func WriteSupportBundle(w io.Writer) error {
+	diagnostics.mu.Lock()
+	decisions := append([]CheckDecision(nil), diagnostics.decisions...)
+	samples := append([]sample(nil), diagnostics.samples...)
+	configs := make(map[App]resolvedConfig, len(diagnostics.configs))
+	for app, rc := range diagnostics.configs {
+		configs[app] = rc
+	}
+	diagnostics.mu.Unlock()
+
+	zw := zip.NewWriter(w)
+
+	err := writeJSON(zw, "config.json", configs)
+	if err != nil {
+		return err
+	}
+	err = writeJSON(zw, "decisions.json", decisions)
+	if err != nil {
+		return err
+	}
+	err = writeJSON(zw, "samples.json", samples)
+	if err != nil {
+		return err
+	}
+
+	apps := make([]string, 0, len(configs))
+	for app := range configs {
+		apps = append(apps, string(app))
+	}
+	sort.Strings(apps)
+	for _, app := range apps {
+		path, err := StatePath(App(app))
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		data, err = redactState(data)
+		if err != nil {
+			continue
+		}
+		f, err := zw.Create("state/" + app + ".json")
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// writeJSON writes v to a file called name in the zip archive.
+func writeJSON(zw *zip.Writer, name string, v any) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,56 @@
+package updatecheck
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// tenantPattern matches tenants which are valid DNS labels, so that a tenant
+// can't change the host or path of the endpoint it is substituted into.
+var tenantPattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// expandTenant resolves a URL template such as "https://update.{{.Tenant}}.example.com/check"
+// using tenant. URLs without template actions are returned unchanged.
+func expandTenant(u string, tenant string) (string, error) {
+	if !strings.Contains(u, "{{") {
+		return u, nil
+	}
+	if tenant == "" {
+		return "", fmt.Errorf("%q requires a tenant, set one with WithTenant()", u)
+	}
+	t, err := template.New("url").Option("missingkey=error").Parse(u)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	err = t.Execute(&b, struct{ Tenant string }{Tenant: tenant})
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// resolveTenant expands the tenant in the URL templates.
+func (o *Options) resolveTenant() error {
+	if o.Tenant != "" && !tenantPattern.MatchString(o.Tenant) {
+		return &OptionError{Option: "Tenant", Reason: fmt.Sprintf("%q must contain only letters, digits and hyphens", o.Tenant)}
+	}
+	u, err := expandTenant(o.URL, o.Tenant)
+	if err != nil {
+		return &OptionError{Option: "URL", Reason: err.Error()}
+	}
+	o.URL = u
+	// copy the URLs rather than modifying a slice which the caller may share.
+	var additional []string
+	for _, au := range o.AdditionalURLs {
+		u, err := expandTenant(au, o.Tenant)
+		if err != nil {
+			return &OptionError{Option: "AdditionalURLs", Reason: err.Error()}
+		}
+		additional = append(additional, u)
+	}
+	o.AdditionalURLs = additional
+	return nil
+}
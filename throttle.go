@@ -0,0 +1,133 @@
+package updatecheck
+
+import (
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// skipReason returns a description of why the check should be skipped,
+// or an empty string if the check should go ahead.
+func skipReason(app App, vc versionConfig, o Options, now time.Time) string {
+	if o.frequency == frequencyAlways {
+		return ""
+	}
+	// if the wall clock can't be trusted, fall back to the monotonic clock within this process.
+	if o.ProcessThrottle || clockIsImplausible(now) {
+		// a check recorded in the future is ignored, as the clock set with WithClock has moved backwards.
+		pnow := o.now()
+		if last, ok := lastProcessCheck(app); ok && !last.After(pnow) && pnow.Before(o.throttle().Next(last)) {
+			return "skipping update check as one ran recently in this process"
+		}
+	}
+	if o.ImageBuildDateEnv != "" {
+		if built, ok := imageBuildDate(o.log(), o.ImageBuildDateEnv); ok && now.Sub(built) < o.ImageMinAge {
+			return "skipping update check as the container image was built at " + built.Format(time.RFC3339)
+		}
+	}
+	if o.FirstCheckDelay > 0 && now.Before(vc.FirstSeen.Add(o.FirstCheckDelay)) {
+		return "skipping update check until the first check delay has passed"
+	}
+	if next := o.throttle().Next(vc.LastCheck); now.Before(next) {
+		return "skipping update check until " + next.Format(time.RFC3339)
+	}
+	return ""
+}
+
+// Throttle decides how often update checks run.
+type Throttle interface {
+	// Next returns the earliest time that the next check may run,
+	// after the last successful check at 'last'. If no check has run, last is zero.
+	// Next must return the same time when called again with the same 'last'.
+	Next(last time.Time) time.Time
+}
+
+// throttle returns the configured throttle, defaulting to checking once per check interval.
+func (o Options) throttle() Throttle {
+	if o.Throttle != nil {
+		return o.Throttle
+	}
+	return intervalThrottle{interval: o.CheckInterval, jitter: o.Jitter}
+}
+
+// Interval returns a Throttle which checks at most once per interval.
+func Interval(d time.Duration) Throttle {
+	return intervalThrottle{interval: d}
+}
+
+// intervalThrottle checks at most once per interval, delayed by a random fraction of the interval
+// if jitter is configured so that checks are spread out across the fleet.
+type intervalThrottle struct {
+	interval time.Duration
+	jitter   float64
+}
+
+func (t intervalThrottle) Next(last time.Time) time.Time {
+	if last.IsZero() {
+		return last
+	}
+	next := last.Add(t.interval)
+	if t.jitter <= 0 {
+		return next
+	}
+	// the jitter is seeded from the time of the last check, so that it is the same each time it is calculated.
+	rnd := rand.New(rand.NewSource(last.UnixNano()))
+	jitter := time.Duration(rnd.Float64() * t.jitter * float64(t.interval))
+	return next.Add(jitter)
+}
+
+// Daily returns a Throttle which checks at most once per calendar day, in local time.
+func Daily() Throttle {
+	return dailyThrottle{}
+}
+
+type dailyThrottle struct{}
+
+func (dailyThrottle) Next(last time.Time) time.Time {
+	if last.IsZero() {
+		return last
+	}
+	last = last.Local()
+	return time.Date(last.Year(), last.Month(), last.Day()+1, 0, 0, 0, 0, last.Location())
+}
+
+// Always returns a Throttle which checks on every invocation.
+func Always() Throttle {
+	return alwaysThrottle{}
+}
+
+type alwaysThrottle struct{}
+
+func (alwaysThrottle) Next(time.Time) time.Time {
+	return time.Time{}
+}
+
+// Never returns a Throttle which never checks for updates.
+func Never() Throttle {
+	return neverThrottle{}
+}
+
+type neverThrottle struct{}
+
+func (neverThrottle) Next(time.Time) time.Time {
+	return time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+}
+
+// imageBuildDate reads a container image build date from the environment variable name.
+// The date may be in RFC 3339 format or a Unix timestamp, such as SOURCE_DATE_EPOCH.
+func imageBuildDate(log Logger, name string) (time.Time, bool) {
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return time.Time{}, false
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, true
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(secs, 0), true
+	}
+	log.Debugf("could not parse container image build date from %s: %q", name, v)
+	return time.Time{}, false
+}
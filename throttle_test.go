@@ -0,0 +1,72 @@
+package updatecheck
+
+import (
+	"testing"
+	"time"
+)
+
+// skipReasonAllocs is the allocation budget for the throttle decision,
+// which runs at the start of every invocation of the calling CLI.
+const skipReasonAllocs = 4
+
+func TestSkipReasonAllocs(t *testing.T) {
+	isolateState(t)
+	now := time.Now()
+	vc := recentlyChecked(t, GrantedCLI, "v0.20.0", now)
+	o, err := NewOptions(true, WithLogger(DiscardLogger))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if skipReason(GrantedCLI, vc, o, now) == "" {
+		t.Fatal("expected the check to be throttled")
+	}
+	for name, at := range map[string]time.Time{"throttled": now, "due": now.Add(48 * time.Hour)} {
+		if allocs := testing.AllocsPerRun(100, func() { skipReason(GrantedCLI, vc, o, at) }); allocs > skipReasonAllocs {
+			t.Errorf("%s: skipReason made %v allocations, the budget is %d", name, allocs, skipReasonAllocs)
+		}
+	}
+}
+
+func BenchmarkSkipReason(b *testing.B) {
+	isolateState(b)
+	now := time.Now()
+	vc := recentlyChecked(b, GrantedCLI, "v0.20.0", now)
+	o, err := NewOptions(true, WithLogger(DiscardLogger))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		skipReason(GrantedCLI, vc, o, now)
+	}
+}
+
+func TestProcessThrottle(t *testing.T) {
+	const app App = "process-throttle-test"
+	now := time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC)
+	recordProcessCheck(app, now.Add(-time.Hour))
+
+	tests := []struct {
+		name     string
+		throttle Throttle
+		skip     bool
+	}{
+		{name: "interval not passed", throttle: Interval(2 * time.Hour), skip: true},
+		{name: "interval passed", throttle: Interval(30 * time.Minute)},
+		{name: "always", throttle: Always()},
+		{name: "never", throttle: Never(), skip: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := NewOptions(true, WithLogger(DiscardLogger), WithProcessThrottle(), WithThrottle(tt.throttle), WithClock(func() time.Time { return now }))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := skipReason(app, versionConfig{}, o, now) != ""; got != tt.skip {
+				t.Errorf("skipped = %v, want %v", got, tt.skip)
+			}
+		})
+	}
+}
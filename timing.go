@@ -0,0 +1,85 @@
+package updatecheck
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings records how long each phase of an update check took,
+// to help diagnose slow CLI startup. Phases which didn't run are zero.
+type Timings struct {
+	// StateLoad is the time taken to load the version config from disk.
+	StateLoad time.Duration
+	// Throttle is the time taken to decide whether the check should run.
+	Throttle time.Duration
+	// DNS is the time taken to resolve the update checking endpoint.
+	DNS time.Duration
+	// Connect is the time taken to establish a TCP connection.
+	Connect time.Duration
+	// TLS is the time taken for the TLS handshake.
+	TLS time.Duration
+	// Total is the time taken for the entire check.
+	Total time.Duration
+}
+
+// phaseTimer records the network phases of a request using httptrace.
+// The trace callbacks may be called concurrently when racing connections.
+type phaseTimer struct {
+	mu       sync.Mutex
+	t        *Timings
+	dnsStart time.Time
+	connect  time.Time
+	tls      time.Time
+}
+
+// trace returns a client trace which records the DNS, connect and TLS phases into t.
+func (p *phaseTimer) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.t.DNS = time.Since(p.dnsStart)
+		},
+		ConnectStart: func(network, addr string) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			// when racing connections, measure from the first connection attempt.
+			if p.connect.IsZero() {
+				p.connect = time.Now()
+			}
+		},
+		ConnectDone: func(network, addr string, err error) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			if err == nil {
+				p.t.Connect = time.Since(p.connect)
+			}
+		},
+		TLSHandshakeStart: func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.tls = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.t.TLS = time.Since(p.tls)
+		},
+	}
+}
+
+// reportTimings logs the timings and passes them to the timing hook, if one is configured.
+func reportTimings(t Timings, o Options) {
+	o.log().Debugf("update check timings: state load=%s throttle=%s dns=%s connect=%s tls=%s total=%s",
+		t.StateLoad, t.Throttle, t.DNS, t.Connect, t.TLS, t.Total)
+	if o.OnTimings != nil {
+		o.OnTimings(t)
+	}
+}
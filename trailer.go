@@ -0,0 +1,33 @@
+package updatecheck
+
+import "strings"
+
+const (
+	// defaultTrailerFormat is the trailer shown when an update exists, if no format is configured.
+	defaultTrailerFormat = "↑ {{latestVersion}} available – run `{{upgradeCommand}}`"
+	// defaultTrailerFormatNoCommand is used instead when there's no upgrade command for the install method.
+	defaultTrailerFormatNoCommand = "↑ {{latestVersion}} available"
+)
+
+// trailer returns the single line shown in trailer mode, or an empty string if
+// none of the messages are for an available update.
+func (o Options) trailer(msgs []message) string {
+	for _, msg := range msgs {
+		if !msg.update {
+			continue
+		}
+		cmd := o.upgradeCommand()
+		format := o.TrailerFormat
+		if format == "" && cmd == "" {
+			format = defaultTrailerFormatNoCommand
+		} else if format == "" {
+			format = defaultTrailerFormat
+		}
+		latest := msg.latestVersion
+		if latest == "" {
+			latest = "update"
+		}
+		return strings.NewReplacer("{{latestVersion}}", latest, upgradeCommandPlaceholder, cmd).Replace(format)
+	}
+	return ""
+}
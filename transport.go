@@ -0,0 +1,357 @@
+package updatecheck
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultTransport returns a new HTTP transport tuned for update checks:
+// short dial and TLS handshake timeouts, HTTP/2 enabled, the proxy taken from
+// the environment, and at most a single idle connection kept alive.
+// It can be used by host applications to share the same connection
+// settings for related requests, such as downloading updates.
+func DefaultTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		MaxIdleConns:          1,
+		MaxIdleConnsPerHost:   1,
+		IdleConnTimeout:       30 * time.Second,
+	}
+}
+
+// httpClient returns the client to use for calling the update checking endpoint.
+func (o Options) httpClient() *http.Client {
+	if o.Client != nil {
+		return o.Client
+	}
+	if o.client != nil {
+		return o.client
+	}
+	return o.newHTTPClient()
+}
+
+// newHTTPClient constructs a client from the TLS, proxy and dial settings.
+// NewOptions constructs one for each Options, so that connections are reused
+// across retries and endpoints.
+func (o Options) newHTTPClient() *http.Client {
+	t := DefaultTransport()
+	t.Proxy = o.proxy()
+	if tc := o.tlsConfig(); tc != nil {
+		t.TLSClientConfig = tc
+	}
+
+	var r resolver = net.DefaultResolver
+	dnsTimeout := o.DNSTimeout
+	if o.DoHResolverURL != "" {
+		// the DoH resolver uses the same proxy and TLS settings, but is itself reached using the system resolver.
+		r = dohResolver{
+			url:      o.DoHResolverURL,
+			client:   &http.Client{Transport: t.Clone()},
+			timeout:  o.DNSTimeout,
+			fallback: net.DefaultResolver,
+			log:      o.log(),
+		}
+		// the DoH resolver applies the DNS timeout to itself and to the fallback separately.
+		dnsTimeout = 0
+	}
+
+	d := dialer{
+		dialer: net.Dialer{
+			Timeout:   o.DialTimeout,
+			KeepAlive: 30 * time.Second,
+		},
+		resolver:      r,
+		dnsTimeout:    dnsTimeout,
+		ipPreference:  o.IPPreference,
+		fallbackDelay: o.FallbackDelay,
+		log:           o.log(),
+	}
+	t.DialContext = d.DialContext
+	return &http.Client{Transport: t}
+}
+
+// resolver looks up the IP addresses for a hostname.
+// It is implemented by *net.Resolver and dohResolver.
+type resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// dialer resolves hostnames with its own timeout before dialing,
+// so that a hanging resolver can't consume the entire dial timeout.
+type dialer struct {
+	dialer        net.Dialer
+	resolver      resolver
+	dnsTimeout    time.Duration
+	ipPreference  IPPreference
+	fallbackDelay time.Duration
+	log           Logger
+}
+
+func (d dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.ipPreference == IPv4Only {
+		network = "tcp4"
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return d.dialer.DialContext(ctx, network, addr)
+	}
+
+	ips, err := d.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	primaries, fallbacks := d.partition(ips)
+	if len(primaries) == 0 {
+		return nil, fmt.Errorf("no usable addresses found for %s", host)
+	}
+	if len(fallbacks) == 0 || d.fallbackDelay < 0 {
+		return d.dialSerial(ctx, network, port, append(primaries, fallbacks...))
+	}
+	return d.dialParallel(ctx, network, port, primaries, fallbacks)
+}
+
+// lookup resolves host, giving up after the DNS timeout.
+func (d dialer) lookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if d.dnsTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.dnsTimeout)
+		defer cancel()
+	}
+	ips, err := d.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %w", host, err)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("resolving %s: no addresses found", host)
+	}
+	return ips, nil
+}
+
+// partition splits the resolved addresses by IP version according to the IP preference.
+// The primaries are dialed first, and the fallbacks are raced against them after the fallback delay.
+func (d dialer) partition(ips []net.IPAddr) (primaries, fallbacks []net.IPAddr) {
+	var v4, v6 []net.IPAddr
+	for _, ip := range ips {
+		if ip.IP.To4() != nil {
+			v4 = append(v4, ip)
+		} else {
+			v6 = append(v6, ip)
+		}
+	}
+
+	switch d.ipPreference {
+	case IPv4Only:
+		return v4, nil
+	case PreferIPv4:
+		// IPv6-only hosts are still reachable, only IPv4Only drops IPv6.
+		if len(v4) == 0 {
+			return v6, nil
+		}
+		return v4, v6
+	}
+
+	// like the standard library, prefer the address family of the first resolved address.
+	if ips[0].IP.To4() != nil {
+		return v4, v6
+	}
+	return v6, v4
+}
+
+// dialSerial tries each address in turn, returning the last error if none can be dialed.
+func (d dialer) dialSerial(ctx context.Context, network, port string, ips []net.IPAddr) (net.Conn, error) {
+	var err error
+	for _, ip := range ips {
+		var conn net.Conn
+		conn, err = d.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+// dialParallel races the primary addresses against the fallback addresses,
+// starting the fallbacks after the fallback delay or as soon as the primaries fail.
+// This is the "Happy Eyeballs" algorithm described in RFC 6555.
+func (d dialer) dialParallel(ctx context.Context, network, port string, primaries, fallbacks []net.IPAddr) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type dialResult struct {
+		conn    net.Conn
+		err     error
+		primary bool
+	}
+	results := make(chan dialResult, 2)
+	start := func(ips []net.IPAddr, primary bool) {
+		go func() {
+			conn, err := d.dialSerial(ctx, network, port, ips)
+			results <- dialResult{conn: conn, err: err, primary: primary}
+		}()
+	}
+
+	delay := d.fallbackDelay
+	if delay == 0 {
+		delay = 300 * time.Millisecond
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	start(primaries, true)
+	outstanding := 1
+	fallbackStarted := false
+	var firstErr error
+
+	for {
+		select {
+		case <-timer.C:
+			if !fallbackStarted {
+				start(fallbacks, false)
+				fallbackStarted = true
+				outstanding++
+			}
+
+		case res := <-results:
+			outstanding--
+			if res.err == nil {
+				// close any connection which completes after the winner.
+				go func(n int) {
+					for i := 0; i < n; i++ {
+						if r := <-results; r.conn != nil {
+							r.conn.Close()
+						}
+					}
+				}(outstanding)
+				return res.conn, nil
+			}
+
+			d.log.Debugf("dialing %s addresses failed: %s", ipVersion(res.primary, primaries, fallbacks), res.err.Error())
+			if firstErr == nil {
+				firstErr = res.err
+			}
+			if !fallbackStarted {
+				start(fallbacks, false)
+				fallbackStarted = true
+				outstanding++
+			} else if outstanding == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
+
+// ipVersion describes the IP version of the primary or fallback addresses, for logging.
+func ipVersion(primary bool, primaries, fallbacks []net.IPAddr) string {
+	ips := fallbacks
+	if primary {
+		ips = primaries
+	}
+	if ips[0].IP.To4() != nil {
+		return "IPv4"
+	}
+	return "IPv6"
+}
+
+// proxy returns the function used to choose a proxy for each request, which logs
+// how the proxy was resolved so that failures behind corporate proxies are diagnosable.
+func (o Options) proxy() func(*http.Request) (*url.URL, error) {
+	proxy := o.Proxy
+	source := "custom proxy function"
+	if proxy == nil {
+		proxy = http.ProxyFromEnvironment
+		source = fmt.Sprintf("HTTPS_PROXY=%q HTTP_PROXY=%q NO_PROXY=%q",
+			redactProxy(envAny("HTTPS_PROXY", "https_proxy")), redactProxy(envAny("HTTP_PROXY", "http_proxy")), envAny("NO_PROXY", "no_proxy"))
+	}
+	log := o.log()
+	return func(req *http.Request) (*url.URL, error) {
+		u, err := proxy(req)
+		switch {
+		case err != nil:
+			log.Debugf("error resolving proxy for %s from %s: %s", req.URL.Host, source, err.Error())
+		case u == nil:
+			log.Debugf("not using a proxy for %s (%s)", req.URL.Host, source)
+		default:
+			log.Debugf("using proxy %s for %s (%s)", u.Redacted(), req.URL.Host, source)
+		}
+		return u, err
+	}
+}
+
+// envAny returns the value of the first of the environment variables which is set.
+func envAny(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// redactProxy removes any credentials from a proxy environment variable,
+// which may or may not include a scheme.
+func redactProxy(v string) string {
+	i := strings.LastIndex(v, "@")
+	if i == -1 {
+		return v
+	}
+	scheme := ""
+	if j := strings.Index(v, "://"); j != -1 && j < i {
+		scheme = v[:j+3]
+	}
+	return scheme + "redacted@" + v[i+1:]
+}
+
+// tlsConfig returns the TLS configuration to use, adding the certificates in the CA bundle
+// to the system's roots if one is configured. It returns nil to use the default configuration.
+func (o Options) tlsConfig() *tls.Config {
+	if o.CABundlePath == "" {
+		return o.TLSConfig
+	}
+	tc := &tls.Config{}
+	if o.TLSConfig != nil {
+		tc = o.TLSConfig.Clone()
+	}
+
+	pem, err := os.ReadFile(o.CABundlePath)
+	if err != nil {
+		o.log().Debugf("error reading CA bundle, using the system roots: %s", err.Error())
+		return o.TLSConfig
+	}
+	// clone the pool rather than adding the bundle to the caller's own TLSConfig.
+	var pool *x509.CertPool
+	if tc.RootCAs != nil {
+		pool = tc.RootCAs.Clone()
+	} else {
+		pool, err = x509.SystemCertPool()
+		if err != nil {
+			o.log().Debugf("error loading system roots, using only the CA bundle: %s", err.Error())
+			pool = x509.NewCertPool()
+		}
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		o.log().Debugf("no certificates found in CA bundle %s", o.CABundlePath)
+	}
+	tc.RootCAs = pool
+	return tc
+}
@@ -0,0 +1,198 @@
+package updatecheck
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPartition(t *testing.T) {
+	v4 := net.IPAddr{IP: net.ParseIP("192.0.2.1")}
+	v6 := net.IPAddr{IP: net.ParseIP("2001:db8::1")}
+	for _, tc := range []struct {
+		name          string
+		pref          IPPreference
+		ips           []net.IPAddr
+		wantPrimaries []net.IPAddr
+		wantFallbacks []net.IPAddr
+	}{
+		{name: "default v6 first", pref: IPDefault, ips: []net.IPAddr{v6, v4}, wantPrimaries: []net.IPAddr{v6}, wantFallbacks: []net.IPAddr{v4}},
+		{name: "default v4 first", pref: IPDefault, ips: []net.IPAddr{v4, v6}, wantPrimaries: []net.IPAddr{v4}, wantFallbacks: []net.IPAddr{v6}},
+		{name: "prefer v4", pref: PreferIPv4, ips: []net.IPAddr{v6, v4}, wantPrimaries: []net.IPAddr{v4}, wantFallbacks: []net.IPAddr{v6}},
+		{name: "prefer v4 with only v6", pref: PreferIPv4, ips: []net.IPAddr{v6}, wantPrimaries: []net.IPAddr{v6}},
+		{name: "v4 only", pref: IPv4Only, ips: []net.IPAddr{v6, v4}, wantPrimaries: []net.IPAddr{v4}},
+		{name: "v4 only with only v6", pref: IPv4Only, ips: []net.IPAddr{v6}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			primaries, fallbacks := dialer{ipPreference: tc.pref}.partition(tc.ips)
+			if !sameIPs(primaries, tc.wantPrimaries) {
+				t.Errorf("primaries = %v, want %v", primaries, tc.wantPrimaries)
+			}
+			if !sameIPs(fallbacks, tc.wantFallbacks) {
+				t.Errorf("fallbacks = %v, want %v", fallbacks, tc.wantFallbacks)
+			}
+		})
+	}
+}
+
+func sameIPs(a, b []net.IPAddr) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].IP.Equal(b[i].IP) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTLSConfigDoesNotModifyRootCAs(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(srv.Close)
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	err := os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	cfg := &tls.Config{RootCAs: roots}
+	o, err := NewOptions(true, WithTLSConfig(cfg), WithCABundle(bundle), WithLogger(DiscardLogger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc := o.tlsConfig()
+	if tc.RootCAs == roots {
+		t.Fatal("the CA bundle was added to the caller's RootCAs")
+	}
+	if !roots.Equal(x509.NewCertPool()) {
+		t.Error("the caller's RootCAs were modified")
+	}
+	if _, err := srv.Certificate().Verify(x509.VerifyOptions{Roots: tc.RootCAs}); err != nil {
+		t.Errorf("the CA bundle isn't trusted: %s", err)
+	}
+}
+
+func TestCustomClientValidation(t *testing.T) {
+	client := func(o *Options) { o.Client = &http.Client{} }
+	tests := []struct {
+		name string
+		opt  func(*Options)
+	}{
+		{name: "TLS config", opt: WithTLSConfig(&tls.Config{})},
+		{name: "CA bundle", opt: WithCABundle("ca.pem")},
+		{name: "proxy", opt: WithProxy(http.ProxyFromEnvironment)},
+		{name: "DoH resolver", opt: WithDoHResolver("https://dns.example.com/dns-query")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewOptions(true, client, tt.opt)
+			var oe *OptionError
+			if !errors.As(err, &oe) || oe.Option != "Client" {
+				t.Fatalf("NewOptions() error = %v, want an OptionError for Client", err)
+			}
+		})
+	}
+}
+
+func TestDoHUsesConfiguredTransport(t *testing.T) {
+	updates := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(updates.Close)
+	u, _ := url.Parse(updates.URL)
+	_, port, _ := net.SplitHostPort(u.Host)
+
+	// the DoH resolver uses a certificate which is only trusted by the configured TLSConfig.
+	doh := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("type") == "1" {
+			_, _ = w.Write([]byte(`{"Status":0,"Answer":[{"type":1,"data":"127.0.0.1"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"Status":0}`))
+	}))
+	t.Cleanup(doh.Close)
+	roots := x509.NewCertPool()
+	roots.AddCert(doh.Certificate())
+
+	var mu sync.Mutex
+	var proxied []string
+	o, err := NewOptions(true,
+		WithDoHResolver(doh.URL),
+		WithTLSConfig(&tls.Config{RootCAs: roots}),
+		WithProxy(func(r *http.Request) (*url.URL, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			proxied = append(proxied, r.URL.Host)
+			return nil, nil
+		}),
+		WithLogger(DiscardLogger),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := o.httpClient().Get("http://updates.test:" + port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	dohHost := doh.Listener.Addr().String()
+	found := false
+	for _, h := range proxied {
+		if h == dohHost {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("the proxy was consulted for %q, want it to include the DoH resolver %s", proxied, dohHost)
+	}
+}
+
+func TestRetriesReuseConnection(t *testing.T) {
+	isolateState(t)
+	var mu sync.Mutex
+	var requests, conns int
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		mu.Unlock()
+		if n < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"updateRequired":false,"message":""}`))
+	}))
+	srv.Config.ConnState = func(c net.Conn, s http.ConnState) {
+		if s == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	runCheck(t, testApp, "v0.20.0", testOptions(srv.URL, WithRetries(2, time.Millisecond))...)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if requests != 3 {
+		t.Fatalf("got %d requests, want 3", requests)
+	}
+	if conns != 1 {
+		t.Errorf("got %d connections, want the retries to reuse 1", conns)
+	}
+}
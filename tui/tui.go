@@ -0,0 +1,144 @@
+// Package tui provides a bubbletea component which displays update notices
+// from updatecheck.CheckNow, for CLIs with a terminal user interface.
+//
+// The component shows the update message and the message's actions, and runs
+// an action when the user presses its number. 's' snoozes update messages and
+// 'u' runs the upgrade, if the message offers them, and 'esc' dismisses the notice.
+//
+// It is a separate module, so that applications which don't use it don't depend on bubbletea.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/common-fate/updatecheck"
+)
+
+// Model displays an update notice. Embed it in the host application's model,
+// passing messages to Update and including View in the host's view.
+type Model struct {
+	app    updatecheck.App
+	result *updatecheck.Result
+	opts   []func(*updatecheck.Options)
+
+	// running is the label of the action which is running, such as the upgrade.
+	running string
+	status  string
+	hidden  bool
+}
+
+// New returns a Model which displays res, the result of a check for app.
+// The options should be the same as those used for the check, including
+// WithUpgradeFunc() if the notice should offer to upgrade.
+func New(app updatecheck.App, res *updatecheck.Result, opts ...func(*updatecheck.Options)) Model {
+	return Model{app: app, result: res, opts: opts}
+}
+
+// actionDoneMsg is sent when an action finishes running.
+type actionDoneMsg struct {
+	action updatecheck.Action
+	err    error
+}
+
+// Init implements tea.Model.
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles keypresses for the notice's actions.
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	if !m.Visible() {
+		return m, nil
+	}
+	switch msg := msg.(type) {
+	case actionDoneMsg:
+		m.running = ""
+		switch {
+		case msg.err != nil:
+			m.status = fmt.Sprintf("%s failed: %s", msg.action.Label, msg.err.Error())
+		case msg.action.Type == updatecheck.ActionSnooze:
+			m.hidden = true
+		case msg.action.Type == updatecheck.ActionRunUpgrade:
+			m.status = "Upgraded, restart to use the new version."
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.running != "" {
+			return m, nil
+		}
+		key := msg.String()
+		switch key {
+		case "esc":
+			m.hidden = true
+			return m, nil
+		case "s":
+			return m.run(m.actionOfType(updatecheck.ActionSnooze))
+		case "u":
+			return m.run(m.actionOfType(updatecheck.ActionRunUpgrade))
+		}
+		if len(key) == 1 && key[0] >= '1' && int(key[0]-'0') <= len(m.result.Actions) {
+			return m.run(&m.result.Actions[key[0]-'1'])
+		}
+	}
+	return m, nil
+}
+
+// actionOfType returns the first action of type t, or nil if the message doesn't offer one.
+func (m Model) actionOfType(t updatecheck.ActionType) *updatecheck.Action {
+	for i := range m.result.Actions {
+		if m.result.Actions[i].Type == t {
+			return &m.result.Actions[i]
+		}
+	}
+	return nil
+}
+
+// run starts running an action in the background.
+func (m Model) run(a *updatecheck.Action) (Model, tea.Cmd) {
+	if a == nil {
+		return m, nil
+	}
+	action := *a
+	m.running = action.Label
+	m.status = ""
+	return m, func() tea.Msg {
+		err := updatecheck.RunAction(context.Background(), m.app, action, m.opts...)
+		return actionDoneMsg{action: action, err: err}
+	}
+}
+
+// Visible returns true if there is a notice to display, which may be a security
+// advisory for the running version even if no update is available.
+func (m Model) Visible() bool {
+	return m.result != nil && m.result.Message != "" && !m.hidden
+}
+
+// View renders the update notice, or an empty string if there isn't one.
+func (m Model) View() string {
+	if !m.Visible() {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(m.result.Message)
+	b.WriteString("\n")
+
+	var actions []string
+	for i, a := range m.result.Actions {
+		actions = append(actions, fmt.Sprintf("[%d] %s", i+1, a.Label))
+	}
+	actions = append(actions, "[esc] Dismiss")
+	b.WriteString(strings.Join(actions, "  "))
+	b.WriteString("\n")
+
+	switch {
+	case m.running != "":
+		fmt.Fprintf(&b, "%s...\n", m.running)
+	case m.status != "":
+		b.WriteString(m.status + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,142 @@
+package tui
+
+import (
+	"errors"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/common-fate/updatecheck"
+)
+
+var (
+	releaseNotes = updatecheck.Action{Label: "Release notes", Type: updatecheck.ActionOpenURL, URL: "https://granted.dev/changelog"}
+	snooze       = updatecheck.Action{Label: "Remind me later", Type: updatecheck.ActionSnooze, Duration: "72h"}
+	upgrade      = updatecheck.Action{Label: "Upgrade", Type: updatecheck.ActionRunUpgrade}
+)
+
+func updateAvailable() *updatecheck.Result {
+	return &updatecheck.Result{
+		UpdateRequired: true,
+		LatestVersion:  "v0.21.0",
+		Message:        "A new version is available: v0.21.0",
+		Actions:        []updatecheck.Action{releaseNotes, snooze, upgrade},
+	}
+}
+
+func TestVisible(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *updatecheck.Result
+		want   bool
+	}{
+		{name: "no result", result: nil, want: false},
+		{name: "up to date", result: &updatecheck.Result{}, want: false},
+		{name: "update available", result: updateAvailable(), want: true},
+		{
+			name: "security advisory without an update",
+			result: &updatecheck.Result{
+				Message:  "v0.20.0 is affected by a security vulnerability",
+				Severity: updatecheck.SeverityCritical,
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(updatecheck.GrantedCLI, tt.result)
+			if got := m.Visible(); got != tt.want {
+				t.Errorf("Visible() = %v, want %v", got, tt.want)
+			}
+			if got := m.View() != ""; got != tt.want {
+				t.Errorf("View() = %q, want visible = %v", m.View(), tt.want)
+			}
+		})
+	}
+}
+
+func key(s string) tea.Msg {
+	if s == "esc" {
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name        string
+		result      *updatecheck.Result
+		running     string
+		msg         tea.Msg
+		wantCmd     bool
+		wantRunning string
+		wantStatus  string
+		wantVisible bool
+	}{
+		{name: "esc dismisses", result: updateAvailable(), msg: key("esc"), wantVisible: false},
+		{name: "number runs action", result: updateAvailable(), msg: key("1"), wantCmd: true, wantRunning: "Release notes", wantVisible: true},
+		{name: "number out of range", result: updateAvailable(), msg: key("4"), wantVisible: true},
+		{name: "s snoozes", result: updateAvailable(), msg: key("s"), wantCmd: true, wantRunning: "Remind me later", wantVisible: true},
+		{name: "u upgrades", result: updateAvailable(), msg: key("u"), wantCmd: true, wantRunning: "Upgrade", wantVisible: true},
+		{
+			name:        "u without an upgrade action",
+			result:      &updatecheck.Result{UpdateRequired: true, Message: "A new version is available"},
+			msg:         key("u"),
+			wantVisible: true,
+		},
+		{name: "keys ignored while running", result: updateAvailable(), running: "Upgrade", msg: key("esc"), wantRunning: "Upgrade", wantVisible: true},
+		{name: "keys ignored when hidden", result: &updatecheck.Result{}, msg: key("1"), wantVisible: false},
+		{
+			name:        "snooze finished",
+			result:      updateAvailable(),
+			running:     "Remind me later",
+			msg:         actionDoneMsg{action: snooze},
+			wantVisible: false,
+		},
+		{
+			name:        "upgrade finished",
+			result:      updateAvailable(),
+			running:     "Upgrade",
+			msg:         actionDoneMsg{action: upgrade},
+			wantStatus:  "Upgraded, restart to use the new version.",
+			wantVisible: true,
+		},
+		{
+			name:        "action failed",
+			result:      updateAvailable(),
+			running:     "Upgrade",
+			msg:         actionDoneMsg{action: upgrade, err: errors.New("permission denied")},
+			wantStatus:  "Upgrade failed: permission denied",
+			wantVisible: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(updatecheck.GrantedCLI, tt.result)
+			m.running = tt.running
+			m, cmd := m.Update(tt.msg)
+			if (cmd != nil) != tt.wantCmd {
+				t.Errorf("Update() returned cmd = %v, want a cmd = %v", cmd != nil, tt.wantCmd)
+			}
+			if m.running != tt.wantRunning {
+				t.Errorf("running = %q, want %q", m.running, tt.wantRunning)
+			}
+			if m.status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", m.status, tt.wantStatus)
+			}
+			if m.Visible() != tt.wantVisible {
+				t.Errorf("Visible() = %v, want %v", m.Visible(), tt.wantVisible)
+			}
+		})
+	}
+}
+
+func TestView(t *testing.T) {
+	m := New(updatecheck.GrantedCLI, updateAvailable())
+	m, _ = m.Update(key("u"))
+	want := "A new version is available: v0.21.0\n" +
+		"[1] Release notes  [2] Remind me later  [3] Upgrade  [esc] Dismiss\n" +
+		"Upgrade...\n"
+	if got := m.View(); got != want {
+		t.Errorf("View() = %q, want %q", got, want)
+	}
+}
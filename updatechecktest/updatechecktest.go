@@ -0,0 +1,257 @@
+// Package updatechecktest provides a fake update service, response builders and a
+// deterministic clock, so that CLIs can test their update UX without calling the real
+// update service or modifying the user's update checking state. The fake service can
+// inject failures, such as slow responses, timeouts, server errors, malformed JSON and
+// truncated bodies, to test that the CLI degrades gracefully.
+//
+//	func TestUpdateNotice(t *testing.T) {
+//		srv := updatechecktest.NewServer(t)
+//		srv.Respond(updatechecktest.UpdateAvailable("v0.21.0", "A new version is available"))
+//		updatechecktest.IsolateState(t)
+//
+//		c, _ := updatecheck.New(updatecheck.GrantedCLI, "v0.20.0", srv.Options()...)
+//		c.Check()
+//		var buf bytes.Buffer
+//		c.Fprint(&buf)
+//		...
+//	}
+package updatechecktest
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/common-fate/updatecheck"
+)
+
+// Server is a fake update service which records the requests it receives.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	response updatecheck.Response
+	status   int
+	key      ed25519.PrivateKey
+	fault    Fault
+	latency  time.Duration
+	requests []updatecheck.Request
+}
+
+// Fault is a failure which the fake server injects into its responses,
+// for testing that the CLI degrades gracefully.
+type Fault int
+
+const (
+	// NoFault responds normally.
+	NoFault Fault = iota
+	// FaultMalformedJSON responds with a body which isn't valid JSON.
+	FaultMalformedJSON
+	// FaultPartialBody closes the connection part way through the response body.
+	FaultPartialBody
+	// FaultHang doesn't respond until the request is abandoned, so that the client times out.
+	FaultHang
+)
+
+// NewServer starts a fake update service which responds that no update is available,
+// until a response is set with Respond. Responses have an ETag, and the server responds
+// with 304 Not Modified to requests with a matching X-Updatecheck-If-None-Match header.
+// The server is closed when the test finishes.
+func NewServer(t testing.TB) *Server {
+	s := &Server{status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var req updatecheck.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	res, status, key, fault, latency := s.response, s.status, s.key, s.fault, s.latency
+	s.mu.Unlock()
+
+	if fault == FaultHang {
+		<-r.Context().Done()
+		return
+	}
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if fault == FaultMalformedJSON {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"updateRequired": tru`))
+		return
+	}
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+		return
+	}
+	body, err := json.Marshal(res)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if key != nil {
+		// the signature binds the body to the request, so that it can't be replayed.
+		payload := "updatecheck-response-v1\n" + string(req.Application) + "\n" + req.Version + "\n" + r.Header.Get("X-Updatecheck-Nonce") + "\n" + string(body)
+		w.Header().Set("X-Signature", base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(payload))))
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	// the client sends its stored ETag in a custom header, as If-None-Match on a POST
+	// must be answered with 412 rather than 304.
+	if r.Header.Get("X-Updatecheck-If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if fault == FaultPartialBody {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		_, _ = w.Write(body[:len(body)/2])
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		// abort the connection without sending the rest of the body.
+		panic(http.ErrAbortHandler)
+	}
+	_, _ = w.Write(body)
+}
+
+// Respond sets the response to later requests. It clears any status set with
+// RespondWithStatus and any fault injected with Inject, but not the latency.
+func (s *Server) Respond(res updatecheck.Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.response = res
+	s.status = http.StatusOK
+	s.fault = NoFault
+}
+
+// Inject makes later responses fail with fault. Use NoFault to respond normally again.
+func (s *Server) Inject(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fault = fault
+}
+
+// SetLatency delays later responses by d, for testing slow endpoints.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// RespondWithStatus makes later requests fail with an HTTP status code, such as http.StatusInternalServerError.
+func (s *Server) RespondWithStatus(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+// SignWith signs later responses with key, for testing WithSignedResponses().
+func (s *Server) SignWith(key ed25519.PrivateKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.key = key
+}
+
+// Requests returns the requests which the server has received, oldest first.
+func (s *Server) Requests() []updatecheck.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := make([]updatecheck.Request, len(s.requests))
+	copy(requests, s.requests)
+	return requests
+}
+
+// Options returns options which check for updates using the fake server rather than the
+// real update service, and which run checks even though tests aren't interactive.
+func (s *Server) Options() []func(*updatecheck.Options) {
+	return []func(*updatecheck.Options){
+		func(o *updatecheck.Options) {
+			o.URL = s.URL
+			o.AdditionalURLs = nil
+		},
+		updatecheck.WithInteractive(true),
+	}
+}
+
+// UpdateAvailable returns a response which tells the CLI that latestVersion is available.
+func UpdateAvailable(latestVersion string, message string) updatecheck.Response {
+	return updatecheck.Response{
+		UpdateRequired: true,
+		LatestVersion:  latestVersion,
+		Message:        message,
+	}
+}
+
+// UpToDate returns a response which tells the CLI that no update is available.
+func UpToDate() updatecheck.Response {
+	return updatecheck.Response{}
+}
+
+// SecurityAdvisory returns a response containing a critical notice.
+func SecurityAdvisory(message string) updatecheck.Response {
+	return updatecheck.Response{
+		Messages: []updatecheck.Notice{{Severity: updatecheck.SeverityCritical, Message: message}},
+	}
+}
+
+// IsolateState stores update checking state in a temporary directory for the rest of
+// the test, so that the test doesn't read or modify the user's real state.
+// Tests which use it must not run in parallel.
+func IsolateState(t testing.TB) string {
+	dir := t.TempDir()
+	updatecheck.SetStateDir(dir)
+	t.Cleanup(func() { updatecheck.SetStateDir("") })
+	return dir
+}
+
+// Clock is a deterministic clock for testing throttling, which only moves when advanced.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a Clock which is stopped at now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the clock's current time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Option returns an option which uses the clock as the current time.
+func (c *Clock) Option() func(*updatecheck.Options) {
+	return updatecheck.WithClock(c.Now)
+}
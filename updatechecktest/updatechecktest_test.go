@@ -0,0 +1,210 @@
+package updatechecktest_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/common-fate/updatecheck"
+	"github.com/common-fate/updatecheck/updatechecktest"
+)
+
+// check runs a check of granted-cli v0.20.0 and returns the messages it would print.
+func check(t *testing.T, opts ...func(*updatecheck.Options)) string {
+	t.Helper()
+	opts = append(opts, updatecheck.WithLogger(updatecheck.DiscardLogger))
+	c, err := updatecheck.New(updatecheck.GrantedCLI, "v0.20.0", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Check()
+	var buf bytes.Buffer
+	c.Fprint(&buf)
+	return buf.String()
+}
+
+func TestRespond(t *testing.T) {
+	srv := updatechecktest.NewServer(t)
+	updatechecktest.IsolateState(t)
+	srv.Respond(updatechecktest.UpdateAvailable("v0.21.0", "A new version is available"))
+
+	if got := check(t, srv.Options()...); !strings.Contains(got, "A new version is available") {
+		t.Errorf("output is missing the update message:\n%s", got)
+	}
+	reqs := srv.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("server received %d requests, want 1", len(reqs))
+	}
+	if reqs[0].Application != updatecheck.GrantedCLI || reqs[0].Version != "v0.20.0" {
+		t.Errorf("unexpected request %+v", reqs[0])
+	}
+}
+
+func TestSignWith(t *testing.T) {
+	srv := updatechecktest.NewServer(t)
+	updatechecktest.IsolateState(t)
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.SignWith(priv)
+	srv.Respond(updatechecktest.UpdateAvailable("v0.21.0", "A new version is available"))
+	opts := append(srv.Options(), updatecheck.WithSignedResponses(pub), updatecheck.WithCheckInterval(time.Nanosecond))
+
+	// the second check is answered with 304 Not Modified, which must be signed again.
+	for i := 0; i < 2; i++ {
+		if got := check(t, opts...); !strings.HasPrefix(got, "✓ A new version is available") {
+			t.Errorf("check %d: output isn't verified:\n%s", i, got)
+		}
+	}
+}
+
+func TestRespondWithStatus(t *testing.T) {
+	srv := updatechecktest.NewServer(t)
+	updatechecktest.IsolateState(t)
+	srv.RespondWithStatus(http.StatusInternalServerError)
+
+	opts := append(srv.Options(), updatecheck.WithCheckInterval(time.Nanosecond))
+	if got := check(t, opts...); got != "" {
+		t.Errorf("expected no output when the server fails, got:\n%s", got)
+	}
+
+	srv.Respond(updatechecktest.UpdateAvailable("v0.21.0", "A new version is available"))
+	if got := check(t, opts...); !strings.Contains(got, "A new version is available") {
+		t.Errorf("Respond didn't clear the status, output:\n%s", got)
+	}
+}
+
+func TestRespondClearsFault(t *testing.T) {
+	srv := updatechecktest.NewServer(t)
+	updatechecktest.IsolateState(t)
+	srv.Inject(updatechecktest.FaultMalformedJSON)
+	srv.Respond(updatechecktest.UpdateAvailable("v0.21.0", "A new version is available"))
+
+	if got := check(t, srv.Options()...); !strings.Contains(got, "A new version is available") {
+		t.Errorf("Respond didn't clear the fault, output:\n%s", got)
+	}
+}
+
+func TestClock(t *testing.T) {
+	srv := updatechecktest.NewServer(t)
+	updatechecktest.IsolateState(t)
+	srv.Respond(updatechecktest.UpToDate())
+	clock := updatechecktest.NewClock(time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC))
+	opts := append(srv.Options(), clock.Option(), updatecheck.WithProcessThrottle())
+
+	check(t, opts...)
+	clock.Advance(time.Hour)
+	check(t, opts...)
+	if got := len(srv.Requests()); got != 1 {
+		t.Fatalf("server received %d requests within the check interval, want 1", got)
+	}
+
+	clock.Advance(48 * time.Hour)
+	check(t, opts...)
+	if got := len(srv.Requests()); got != 2 {
+		t.Errorf("server received %d requests after the check interval, want 2", got)
+	}
+}
+
+func TestSnoozeUsesClock(t *testing.T) {
+	srv := updatechecktest.NewServer(t)
+	updatechecktest.IsolateState(t)
+	srv.Respond(updatechecktest.UpdateAvailable("v0.21.0", "A new version is available"))
+	clock := updatechecktest.NewClock(time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC))
+	opts := append(srv.Options(), clock.Option(), updatecheck.WithCheckInterval(time.Nanosecond))
+
+	if err := updatecheck.Snooze(updatecheck.GrantedCLI, time.Hour, opts...); err != nil {
+		t.Fatal(err)
+	}
+	if got := check(t, opts...); got != "" {
+		t.Errorf("expected no output while snoozed, got:\n%s", got)
+	}
+
+	clock.Advance(2 * time.Hour)
+	if got := check(t, opts...); !strings.Contains(got, "A new version is available") {
+		t.Errorf("output is missing the update message after the snooze ended:\n%s", got)
+	}
+}
+
+func TestUserAgentCallerPackage(t *testing.T) {
+	updatechecktest.IsolateState(t)
+	ua := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case ua <- r.UserAgent():
+		default:
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("{}"))
+	}))
+	t.Cleanup(srv.Close)
+
+	check(t, func(o *updatecheck.Options) { o.URL = srv.URL }, updatecheck.WithInteractive(true))
+
+	// the check runs in the background, so the caller must be found before it starts.
+	want := " github.com/common-fate/updatecheck/updatechecktest_test ("
+	select {
+	case got := <-ua:
+		if !strings.Contains(got, want) {
+			t.Errorf("User-Agent = %q, want it to contain %q", got, want)
+		}
+	default:
+		t.Fatal("no request was made")
+	}
+}
+
+func TestIsolateState(t *testing.T) {
+	srv := updatechecktest.NewServer(t)
+	dir := updatechecktest.IsolateState(t)
+	check(t, srv.Options()...)
+
+	path, err := updatecheck.StatePath(updatecheck.GrantedCLI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(path) != dir {
+		t.Errorf("state is stored in %s, want %s", filepath.Dir(path), dir)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("state wasn't saved: %s", err)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	tests := []struct {
+		name   string
+		inject func(*updatechecktest.Server)
+	}{
+		{name: "hang", inject: func(s *updatechecktest.Server) { s.Inject(updatechecktest.FaultHang) }},
+		{name: "malformed JSON", inject: func(s *updatechecktest.Server) { s.Inject(updatechecktest.FaultMalformedJSON) }},
+		{name: "partial body", inject: func(s *updatechecktest.Server) { s.Inject(updatechecktest.FaultPartialBody) }},
+		{name: "server error", inject: func(s *updatechecktest.Server) { s.RespondWithStatus(http.StatusInternalServerError) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := updatechecktest.NewServer(t)
+			updatechecktest.IsolateState(t)
+			srv.Respond(updatechecktest.UpdateAvailable("v0.21.0", "A new version is available"))
+			tt.inject(srv)
+
+			start := time.Now()
+			got := check(t, append(srv.Options(), updatecheck.WithTimeout(100*time.Millisecond))...)
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("check took %s, it should give up after the timeout", elapsed)
+			}
+			if got != "" {
+				t.Errorf("expected no output when the server fails, got:\n%s", got)
+			}
+			if len(srv.Requests()) == 0 {
+				t.Error("server didn't receive a request")
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package updatecheck
+
+import "fmt"
+
+// Verbosity is the output verbosity of the host application,
+// such as set by its --quiet and --verbose flags.
+type Verbosity int
+
+const (
+	// VerbosityQuiet is used when the host application has been asked for minimal output.
+	VerbosityQuiet Verbosity = -1
+	// VerbosityNormal is the default verbosity.
+	VerbosityNormal Verbosity = 0
+	// VerbosityVerbose is used when the host application has been asked for extra detail.
+	// Update messages include the latest version and its release date.
+	VerbosityVerbose Verbosity = 1
+)
+
+// verbosity returns the host application's current verbosity.
+func (o Options) verbosity() Verbosity {
+	if o.Verbosity == nil {
+		return VerbosityNormal
+	}
+	return o.Verbosity()
+}
+
+// detail describes the latest version and its release date, for verbose output.
+func (m message) detail() string {
+	if m.releaseDate.IsZero() {
+		return fmt.Sprintf("Latest version: %s", m.latestVersion)
+	}
+	return fmt.Sprintf("Latest version: %s, released %s", m.latestVersion, m.releaseDate.Format("2 January 2006"))
+}
@@ -0,0 +1,126 @@
+package updatecheck
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// VersionInfo describes the provenance of the running binary and the state of update checks,
+// for CLIs to show in their `version` command.
+type VersionInfo struct {
+	// Version is the running version.
+	Version string `json:"version"`
+	// Commit is the VCS revision which the binary was built from, if the Go toolchain embedded it.
+	Commit string `json:"commit,omitempty"`
+	// Modified is true if the binary was built from a working tree with uncommitted changes.
+	Modified bool `json:"modified,omitempty"`
+	// BuildDate is when the binary was built, if known.
+	BuildDate time.Time `json:"buildDate,omitempty"`
+	// InstallMethod is how the binary was installed, if it could be detected.
+	InstallMethod InstallMethod `json:"installMethod,omitempty"`
+	// SigningEnabled is true if update responses must be signed, configured with WithSignedResponses().
+	SigningEnabled bool `json:"signingEnabled"`
+	// Verified is true if the response to the last check was signed and verified.
+	Verified bool `json:"verified"`
+	// LastCheck is when the last successful update check ran, or zero if none has.
+	LastCheck time.Time `json:"lastCheck,omitempty"`
+	// LatestVersion is the latest version reported by the last check, if the server provided one.
+	LatestVersion string `json:"latestVersion,omitempty"`
+	// UpdateRequired is true if the last check found an update.
+	UpdateRequired bool `json:"updateRequired"`
+}
+
+// GetVersionInfo returns the provenance of the running binary and the state of update checks for app,
+// using only information which has already been gathered, so it doesn't make any network requests.
+// The options should be the same as those passed to Check().
+func GetVersionInfo(app App, currentVersion string, opts ...func(*Options)) VersionInfo {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	vi := VersionInfo{
+		Version:        currentVersion,
+		InstallMethod:  detectInstallMethod(),
+		SigningEnabled: len(o.ResponsePublicKey) > 0,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				vi.Commit = s.Value
+			case "vcs.modified":
+				vi.Modified = s.Value == "true"
+			}
+		}
+	}
+	if built, ok := buildDate(o); ok {
+		vi.BuildDate = built
+	}
+
+	vc, _ := loadVersionConfig(app, o.log())
+	vi.LastCheck = vc.LastCheck
+	for i := len(vc.History) - 1; i >= 0; i-- {
+		e := vc.History[i]
+		if e.Decision != DecisionPerformed {
+			continue
+		}
+		if e.Version == currentVersion {
+			vi.LatestVersion = e.LatestVersion
+			vi.UpdateRequired = e.UpdateRequired
+			vi.Verified = e.Verified
+		}
+		break
+	}
+	return vi
+}
+
+// String formats the version info as an aligned block, such as:
+//
+//	Version:        v0.21.0
+//	Commit:         4f2a9c1
+//	Installed with: homebrew
+//	Last checked:   2023-06-01 09:30 UTC (up to date)
+func (vi VersionInfo) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "Version:\t%s\n", vi.Version)
+	if vi.Commit != "" {
+		commit := vi.Commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		if vi.Modified {
+			commit += " (modified)"
+		}
+		fmt.Fprintf(w, "Commit:\t%s\n", commit)
+	}
+	if !vi.BuildDate.IsZero() {
+		fmt.Fprintf(w, "Built:\t%s\n", vi.BuildDate.UTC().Format("2006-01-02"))
+	}
+	if vi.InstallMethod != InstallUnknown {
+		fmt.Fprintf(w, "Installed with:\t%s\n", vi.InstallMethod)
+	}
+	if vi.LastCheck.IsZero() {
+		fmt.Fprintf(w, "Last checked:\tnever\n")
+	} else {
+		status := "up to date"
+		if vi.UpdateRequired && vi.LatestVersion != "" {
+			status = vi.LatestVersion + " available"
+		} else if vi.UpdateRequired {
+			status = "update available"
+		}
+		fmt.Fprintf(w, "Last checked:\t%s (%s)\n", vi.LastCheck.UTC().Format("2006-01-02 15:04 MST"), status)
+	}
+	if vi.SigningEnabled {
+		signature := "not verified"
+		if vi.Verified {
+			signature = "verified"
+		}
+		fmt.Fprintf(w, "Update signature:\t%s\n", signature)
+	}
+	w.Flush()
+	return b.String()
+}